@@ -0,0 +1,341 @@
+// Package analysis defines an analyzer reporting code that loses the classification of errors tagged with errorutil :
+//
+// - fmt.Errorf calls formatting an error, and errors.New(err.Error()) calls, which drop the tags of err,
+//
+// - closures passed to backoffutil.Retry or RetryContext that never return a retryable error, and will never be retried,
+//
+// - HTTP handlers writing a 500 status code while an error is available, instead of using errorutil.HTTPStatusCode.
+//
+// Only packages importing errorutil are checked. The analyzer is available as the errorutilvet command.
+package analysis
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"net/http"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const (
+	errorutilPath   = "github.com/objenious/errorutil"
+	backoffutilPath = "github.com/objenious/errorutil/backoffutil"
+	errorsPath      = "github.com/objenious/errors"
+)
+
+// Analyzer reports code losing the classification of errors.
+var Analyzer = &analysis.Analyzer{
+	Name:     "errorutil",
+	Doc:      "report code losing the classification of errors tagged with errorutil",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+var errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if !importsErrorutil(pass.Pkg) {
+		return nil, nil
+	}
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn := callee(pass, call)
+		if fn == nil || fn.Pkg() == nil {
+			return
+		}
+		switch path, name := fn.Pkg().Path(), fn.Name(); {
+		case path == "fmt" && name == "Errorf":
+			checkErrorf(pass, call)
+		case (path == "errors" || path == errorsPath) && name == "New":
+			checkNew(pass, call)
+		case path == backoffutilPath && name == "Retry":
+			checkRetry(pass, call, 0)
+		case path == backoffutilPath && name == "RetryContext":
+			checkRetry(pass, call, 1)
+		case path == "net/http" && name == "WriteHeader":
+			checkStatus(pass, call, 0)
+		case path == "net/http" && name == "Error":
+			checkStatus(pass, call, 2)
+		}
+	})
+	return nil, nil
+}
+
+func importsErrorutil(pkg *types.Package) bool {
+	for _, imp := range pkg.Imports() {
+		if strings.HasPrefix(imp.Path(), errorutilPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// callee returns the function or method called, or nil.
+func callee(pass *analysis.Pass, call *ast.CallExpr) *types.Func {
+	var id *ast.Ident
+	switch fun := astutil.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	fn, _ := pass.TypesInfo.Uses[id].(*types.Func)
+	return fn
+}
+
+func isError(pass *analysis.Pass, expr ast.Expr) bool {
+	t := pass.TypesInfo.TypeOf(expr)
+	return t != nil && types.Implements(t, errorType) && !isNil(pass, expr)
+}
+
+func isNil(pass *analysis.Pass, expr ast.Expr) bool {
+	tv, ok := pass.TypesInfo.Types[expr]
+	return ok && tv.IsNil()
+}
+
+// checkErrorf reports fmt.Errorf calls formatting an error.
+// Errors formatted with %w keep their status code, as errorutil.HTTPStatusCode follows Unwrap, but lose their other tags.
+func checkErrorf(pass *analysis.Pass, call *ast.CallExpr) {
+	if len(call.Args) == 0 {
+		return
+	}
+	verbs := formatVerbs(pass, call.Args[0])
+	for i, arg := range call.Args[1:] {
+		if !isError(pass, arg) {
+			continue
+		}
+		if i < len(verbs) && verbs[i] == 'w' {
+			pass.Reportf(arg.Pos(), "fmt.Errorf with %%w keeps the status code of %s, but drops its other classification (retryable, delay, code...), use errors.Wrap from %s", types.ExprString(arg), errorsPath)
+			continue
+		}
+		pass.Reportf(arg.Pos(), "fmt.Errorf drops the classification of %s (retryable, delay, status code...), use errors.Wrap from %s", types.ExprString(arg), errorsPath)
+	}
+}
+
+// formatVerbs returns the verb used for each argument of a constant format string.
+// It returns nil if the format is not a constant, or uses explicit argument indexes.
+func formatVerbs(pass *analysis.Pass, expr ast.Expr) []rune {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return nil
+	}
+	format := constant.StringVal(tv.Value)
+	var verbs []rune
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		// flags, width and precision
+		for i++; i < len(format) && strings.IndexByte("+-# 0123456789.*", format[i]) >= 0; i++ {
+			if format[i] == '*' {
+				verbs = append(verbs, '*')
+			}
+		}
+		if i == len(format) {
+			break
+		}
+		switch format[i] {
+		case '[':
+			return nil
+		case '%':
+		default:
+			verbs = append(verbs, rune(format[i]))
+		}
+	}
+	return verbs
+}
+
+// checkNew reports errors.New(err.Error()) calls.
+func checkNew(pass *analysis.Pass, call *ast.CallExpr) {
+	if len(call.Args) != 1 {
+		return
+	}
+	inner, ok := astutil.Unparen(call.Args[0]).(*ast.CallExpr)
+	if !ok || len(inner.Args) != 0 {
+		return
+	}
+	sel, ok := inner.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Error" || !isError(pass, sel.X) {
+		return
+	}
+	pass.Reportf(call.Pos(), "errors.New(%s.Error()) drops the classification of %s (retryable, delay, status code...)", types.ExprString(sel.X), types.ExprString(sel.X))
+}
+
+// checkRetry reports closures passed to backoffutil.Retry or RetryContext (as argument arg) that only return unclassified errors.
+func checkRetry(pass *analysis.Pass, call *ast.CallExpr, arg int) {
+	if len(call.Args) != arg+1 {
+		return
+	}
+	lit, ok := astutil.Unparen(call.Args[arg]).(*ast.FuncLit)
+	if !ok {
+		return
+	}
+	var returns []*ast.ReturnStmt
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			returns = append(returns, n)
+		}
+		return true
+	})
+	for _, ret := range returns {
+		if len(ret.Results) != 1 || !unclassified(pass, lit.Body, ret.Results[0], map[types.Object]bool{}) {
+			return
+		}
+	}
+	pass.Reportf(lit.Pos(), "function passed to backoffutil.%s never returns a retryable error, it will never be retried", callee(pass, call).Name())
+}
+
+// unclassified reports whether an expression can only be nil or an error without errorutil tags :
+// nil, errors created by the standard library or github.com/objenious/errors, NotRetryableError,
+// or variables only assigned such errors in body.
+func unclassified(pass *analysis.Pass, body *ast.BlockStmt, expr ast.Expr, seen map[types.Object]bool) bool {
+	expr = astutil.Unparen(expr)
+	if isNil(pass, expr) {
+		return true
+	}
+	switch expr := expr.(type) {
+	case *ast.CallExpr:
+		fn := callee(pass, expr)
+		if fn == nil || fn.Pkg() == nil {
+			return false
+		}
+		switch path, name := fn.Pkg().Path(), fn.Name(); {
+		case path == errorutilPath && name == "NotRetryableError":
+			return true
+		case path == errorsPath && (name == "New" || name == "Errorf"):
+			return true
+		case path == errorsPath && len(expr.Args) > 0:
+			// Wrap & co keep the classification of the wrapped error
+			return unclassified(pass, body, expr.Args[0], seen)
+		default:
+			return fn.Pkg() != pass.Pkg && isStd(path)
+		}
+	case *ast.Ident:
+		obj := pass.TypesInfo.Uses[expr]
+		if obj == nil {
+			return false
+		}
+		if seen[obj] {
+			return true
+		}
+		seen[obj] = true
+		values, ok := assignments(pass, body, obj)
+		if !ok {
+			return false
+		}
+		for _, value := range values {
+			if !unclassified(pass, body, value, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// assignments returns the expressions assigned to a variable declared in body.
+// ok is false if the variable is declared outside of body, or if some assignments cannot be analyzed.
+func assignments(pass *analysis.Pass, body *ast.BlockStmt, obj types.Object) (values []ast.Expr, ok bool) {
+	if obj.Pos() < body.Pos() || obj.Pos() >= body.End() {
+		return nil, false
+	}
+	ok = true
+	assign := func(lhs []ast.Expr, rhs []ast.Expr) {
+		for i, l := range lhs {
+			id, isIdent := l.(*ast.Ident)
+			if !isIdent || (pass.TypesInfo.Defs[id] != obj && pass.TypesInfo.Uses[id] != obj) {
+				continue
+			}
+			switch {
+			case len(rhs) == 0:
+				// declared without value, i.e. nil
+			case len(lhs) == len(rhs):
+				values = append(values, rhs[i])
+			case len(rhs) == 1:
+				// multiple values returned by a single call
+				values = append(values, rhs[0])
+			default:
+				ok = false
+			}
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			assign(n.Lhs, n.Rhs)
+		case *ast.ValueSpec:
+			lhs := make([]ast.Expr, len(n.Names))
+			for i, name := range n.Names {
+				lhs[i] = name
+			}
+			assign(lhs, n.Values)
+		case *ast.UnaryExpr:
+			// the address of the variable is taken, it may be assigned anywhere
+			if id, isIdent := n.X.(*ast.Ident); isIdent && n.Op == token.AND && pass.TypesInfo.Uses[id] == obj {
+				ok = false
+			}
+		}
+		return true
+	})
+	return values, ok
+}
+
+func isStd(path string) bool {
+	first := strings.SplitN(path, "/", 2)[0]
+	return !strings.Contains(first, ".")
+}
+
+// checkStatus reports calls writing a 500 status code, while an error is in scope.
+func checkStatus(pass *analysis.Pass, call *ast.CallExpr, arg int) {
+	if len(call.Args) <= arg {
+		return
+	}
+	tv, ok := pass.TypesInfo.Types[call.Args[arg]]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.Int {
+		return
+	}
+	if status, exact := constant.Int64Val(tv.Value); !exact || status != http.StatusInternalServerError {
+		return
+	}
+	name := errorInScope(pass, call.Pos())
+	if name == "" {
+		return
+	}
+	pass.Reportf(call.Pos(), "status code 500 written while error %s is available, use errorutil.HTTPStatusCode(%s) or errorutil.WriteError", name, name)
+}
+
+// errorInScope returns the name of a local variable of type error declared before pos, or an empty string.
+func errorInScope(pass *analysis.Pass, pos token.Pos) string {
+	var file *ast.File
+	for _, f := range pass.Files {
+		if f.Pos() <= pos && pos < f.End() {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return ""
+	}
+	fileScope := pass.TypesInfo.Scopes[file]
+	for scope := fileScope.Innermost(pos); scope != nil && scope != fileScope; scope = scope.Parent() {
+		for _, name := range scope.Names() {
+			obj, isVar := scope.Lookup(name).(*types.Var)
+			if isVar && obj.Pos() < pos && obj.Name() != "_" && types.Identical(obj.Type(), types.Universe.Lookup("error").Type()) {
+				return obj.Name()
+			}
+		}
+	}
+	return ""
+}
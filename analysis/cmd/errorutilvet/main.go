@@ -0,0 +1,16 @@
+// Command errorutilvet reports code losing the classification of errors tagged with errorutil.
+//
+// Usage :
+//
+//	errorutilvet ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/objenious/errorutil/analysis"
+)
+
+func main() {
+	singlechecker.Main(analysis.Analyzer)
+}
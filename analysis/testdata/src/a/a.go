@@ -0,0 +1,94 @@
+package a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	oerrors "github.com/objenious/errors"
+	"github.com/objenious/errorutil"
+	"github.com/objenious/errorutil/backoffutil"
+)
+
+func find() error {
+	return errorutil.NotFoundError(errors.New("not found"))
+}
+
+func wrap() error {
+	err := find()
+	if err != nil {
+		return fmt.Errorf("unable to find: %v", err) // want `fmt.Errorf drops the classification of err`
+	}
+	if err != nil {
+		return errors.New(err.Error()) // want `errors.New\(err.Error\(\)\) drops the classification of err`
+	}
+	if err != nil {
+		return oerrors.New(err.Error()) // want `errors.New\(err.Error\(\)\) drops the classification of err`
+	}
+	if err != nil {
+		return fmt.Errorf("unable to find: %s", err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("unable to find: %w", err) // want `fmt.Errorf with %w keeps the status code of err, but drops its other classification`
+	}
+	if err != nil {
+		return fmt.Errorf("100%% %*d: %v, %w", 3, 42, err, err) // want `fmt.Errorf drops the classification of err` `fmt.Errorf with %w keeps the status code of err`
+	}
+	if err != nil {
+		return fmt.Errorf("%[1]w", err) // want `fmt.Errorf drops the classification of err`
+	}
+	return oerrors.Wrap(err, "unable to find")
+}
+
+func retry() {
+	backoffutil.Retry(func() error { // want `never returns a retryable error`
+		_, err := http.Get("http://www.example.com")
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	backoffutil.Retry(func() error { // want `never returns a retryable error`
+		var err error
+		if err == nil {
+			return oerrors.Wrap(errors.New("foo"), "bar")
+		}
+		return errorutil.NotRetryableError(err)
+	})
+	backoffutil.Retry(func() error {
+		resp, err := http.Get("http://www.example.com")
+		if err != nil {
+			return errorutil.RetryableError(err)
+		}
+		return errorutil.HTTPError(resp)
+	})
+	backoffutil.Retry(func() error {
+		err := find()
+		return err
+	})
+	backoffutil.Retry(find)
+	backoffutil.RetryContext(context.Background(), func() error { // want `function passed to backoffutil.RetryContext never returns a retryable error`
+		_, err := http.Get("http://www.example.com")
+		return err
+	})
+	backoffutil.RetryContext(context.Background(), func() error {
+		return errorutil.RetryableError(find())
+	})
+}
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	if err := find(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError) // want `status code 500 written while error err is available`
+		return
+	}
+	if err := find(); err != nil {
+		http.Error(w, "oops", 500) // want `status code 500 written while error err is available`
+		return
+	}
+	if err := find(); err != nil {
+		w.WriteHeader(errorutil.HTTPStatusCode(err))
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
@@ -0,0 +1,8 @@
+// Package b does not import errorutil, it is not checked.
+package b
+
+import "fmt"
+
+func wrap(err error) error {
+	return fmt.Errorf("foo: %v", err)
+}
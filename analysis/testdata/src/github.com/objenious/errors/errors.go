@@ -0,0 +1,5 @@
+package errors
+
+func New(text string) error                           { return nil }
+func Errorf(format string, args ...interface{}) error { return nil }
+func Wrap(err error, message string) error            { return err }
@@ -0,0 +1,7 @@
+package backoffutil
+
+import "context"
+
+func Retry(fn func() error) error { return fn() }
+
+func RetryContext(ctx context.Context, fn func() error) error { return fn() }
@@ -0,0 +1,9 @@
+package errorutil
+
+import "net/http"
+
+func RetryableError(err error) error      { return err }
+func NotRetryableError(err error) error   { return err }
+func NotFoundError(err error) error       { return err }
+func HTTPError(resp *http.Response) error { return nil }
+func HTTPStatusCode(err error) int        { return 500 }
@@ -1,28 +1,67 @@
 // Package backoffutil provides a wrapper above github.com/cenk/backoff.Retry
 // that checks the error returned and only retries retryable errors.
 //
-// For the sake of simplicity, the backoff strategy is the default exponential backoff.
+// For the sake of simplicity, the backoff strategy is the default exponential backoff,
+// except for errors with a delay (see errorutil.Delay), which are retried after this delay.
 package backoffutil
 
 import (
+	"context"
+	"time"
+
 	"github.com/cenkalti/backoff"
 	"github.com/objenious/errorutil"
 )
 
 // Retry does exponential backoff.
 // Backoff will trigger if an error is returned, implements Retryabler AND the error is retryable.
+// If the error has a delay (see errorutil.Delay), the next attempt waits for this delay instead of the backoff interval.
+// Retrying stops after 15 minutes (the default MaxElapsedTime of backoff.ExponentialBackOff), and the last error is returned.
+//
+// Each retried attempt is reported to the errorutil Metrics, if set.
 func Retry(fn func() error) error {
+	return RetryContext(context.Background(), fn)
+}
+
+// RetryContext is like Retry, but stops retrying when the context is done.
+// The last error returned by fn is then returned.
+func RetryContext(ctx context.Context, fn func() error) error {
 	var finalerr error
-	err := backoff.Retry(func() error {
+	b := &delayBackOff{BackOff: backoff.NewExponentialBackOff(), err: &finalerr}
+	err := backoff.RetryNotify(func() error {
 		finalerr = fn()
 		if errorutil.IsRetryable(finalerr) {
 			return finalerr
 		}
 		return nil
-	}, backoff.NewExponentialBackOff())
+	}, backoff.WithContext(b, ctx), notify)
 
 	if err != nil {
 		return err
 	}
 	return finalerr
 }
+
+// delayBackOff waits for the delay of the last error, if any, instead of the interval of the wrapped BackOff.
+// The wrapped BackOff still decides when to stop retrying.
+type delayBackOff struct {
+	backoff.BackOff
+	err *error
+}
+
+func (b *delayBackOff) NextBackOff() time.Duration {
+	next := b.BackOff.NextBackOff()
+	if next == backoff.Stop {
+		return next
+	}
+	if d := errorutil.Delay(*b.err); d > 0 {
+		return d
+	}
+	return next
+}
+
+func notify(err error, delay time.Duration) {
+	if m := errorutil.GetMetrics(); m != nil {
+		m.Retry(err, delay)
+	}
+}
@@ -1,7 +1,9 @@
 package backoffutil
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/objenious/errorutil"
 )
@@ -20,3 +22,17 @@ func ExampleRetry() {
 		return nil
 	})
 }
+
+func ExampleRetryContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	RetryContext(ctx, func() error {
+		req, _ := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return errorutil.RetryableError(err)
+		}
+		defer resp.Body.Close()
+		return errorutil.HTTPError(resp)
+	})
+}
@@ -0,0 +1,183 @@
+// Package catalog reads declarative error catalogs, and generates Go code and documentation from them.
+//
+// A catalog is a JSON document listing the domain errors of a package :
+//
+//	{
+//	  "package": "users",
+//	  "errors": [
+//	    {
+//	      "code": "user.not_found",
+//	      "kind": "not_found",
+//	      "message": "user {id} not found",
+//	      "fields": ["id"],
+//	      "description": "The user does not exist."
+//	    },
+//	    {
+//	      "code": "db.unavailable",
+//	      "status": 503,
+//	      "retryable": true,
+//	      "delay": "30s",
+//	      "message": "service temporarily unavailable"
+//	    }
+//	  ]
+//	}
+//
+// For each error, the generated code declares a code constant (CodeUserNotFound), a sentinel error (ErrUserNotFound),
+// and constructors (NewUserNotFoundError and UserNotFoundError) returning errors tagged with the code, HTTP status code,
+// retryability, delay and public message. The status code can be replaced by a kind (see errorutil.Kind),
+// in which case the main status code of the kind is used. The message is a template, whose {field} placeholders are replaced
+// by the constructor arguments. Errors built by the constructors match the sentinel error with errors.Is.
+//
+// The errorutilgen command wraps this package.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/objenious/errorutil"
+)
+
+// Catalog is a list of errors.
+type Catalog struct {
+	// Package is the name of the generated Go package.
+	Package string  `json:"package"`
+	Errors  []Entry `json:"errors"`
+}
+
+// Entry declares an error.
+type Entry struct {
+	// Code is the machine-readable code of the error (see errorutil.Code), e.g. "user.not_found".
+	Code string `json:"code"`
+	// Name is used in Go identifiers. If empty, it is derived from the code (e.g. UserNotFound).
+	Name string `json:"name,omitempty"`
+	// Kind is the kind of the error. If empty, it is derived from the status code.
+	Kind errorutil.Kind `json:"kind,omitempty"`
+	// Status is the HTTP status code of the error. If empty, it is the main status code of the kind (see errorutil.KindStatus),
+	// or 500.
+	Status int `json:"status,omitempty"`
+	// Retryable marks the error as retryable. Otherwise, it is explicitly not retryable.
+	Retryable bool `json:"retryable,omitempty"`
+	// Delay is the default delay of the error, e.g. "30s".
+	Delay Duration `json:"delay,omitempty"`
+	// Message is the public message template, whose {field} placeholders are replaced by field values.
+	Message string `json:"message"`
+	// Fields are the parameters of the error constructors.
+	Fields []string `json:"fields,omitempty"`
+	// Description documents the error.
+	Description string `json:"description,omitempty"`
+}
+
+// Duration is a time.Duration, encoded in JSON as a string such as "30s".
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Parse reads and validates a JSON catalog.
+func Parse(data []byte) (*Catalog, error) {
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("catalog: %v", err)
+	}
+	for i := range c.Errors {
+		e := &c.Errors[i]
+		if e.Name == "" {
+			e.Name = nameFromCode(e.Code)
+		}
+		if e.Status == 0 {
+			e.Status = http.StatusInternalServerError
+			if e.Kind != "" {
+				e.Status = errorutil.KindStatus(e.Kind)
+			}
+		}
+		if e.Kind == "" {
+			e.Kind = errorutil.StatusKind(e.Status)
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+var placeholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Validate checks that the catalog can be used to generate code : codes and names must be unique
+// and valid, status codes must be errors matching the kind, and message placeholders must be declared fields.
+func (c *Catalog) Validate() error {
+	if !token.IsIdentifier(c.Package) {
+		return fmt.Errorf("catalog: invalid package name %q", c.Package)
+	}
+	codes := map[string]bool{}
+	names := map[string]bool{}
+	for _, e := range c.Errors {
+		switch {
+		case e.Code == "":
+			return fmt.Errorf("catalog: empty error code")
+		case codes[e.Code]:
+			return fmt.Errorf("catalog: duplicate error code %q", e.Code)
+		case !token.IsIdentifier(e.Name) || !token.IsExported(e.Name):
+			return fmt.Errorf("catalog: %s: invalid name %q", e.Code, e.Name)
+		case names[e.Name]:
+			return fmt.Errorf("catalog: %s: duplicate name %q", e.Code, e.Name)
+		case e.Status < 400 || e.Status > 599:
+			return fmt.Errorf("catalog: %s: invalid error status code %d", e.Code, e.Status)
+		case errorutil.StatusKind(e.Status) != e.Kind:
+			return fmt.Errorf("catalog: %s: kind %q does not match status code %d", e.Code, e.Kind, e.Status)
+		case e.Delay < 0:
+			return fmt.Errorf("catalog: %s: negative delay", e.Code)
+		case e.Message == "":
+			return fmt.Errorf("catalog: %s: empty message", e.Code)
+		}
+		codes[e.Code] = true
+		names[e.Name] = true
+
+		fields := map[string]bool{}
+		for _, f := range e.Fields {
+			if !token.IsIdentifier(f) || token.IsKeyword(f) || f == "err" || fields[f] {
+				return fmt.Errorf("catalog: %s: invalid field %q", e.Code, f)
+			}
+			fields[f] = true
+		}
+		for _, m := range placeholder.FindAllStringSubmatch(e.Message, -1) {
+			if !fields[m[1]] {
+				return fmt.Errorf("catalog: %s: undeclared field %q in message", e.Code, m[1])
+			}
+		}
+	}
+	return nil
+}
+
+// nameFromCode converts a code such as "user.not_found" to a Go name such as "UserNotFound".
+func nameFromCode(code string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(code, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]))
+		b.WriteString(word[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package catalog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/objenious/errorutil"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(`{"package": "foo", "errors": [{"code": "user.not_found", "message": "foo", "delay": "1m"}]}`))
+	if err != nil {
+		t.Fatalf("Parse: unexpected error %v", err)
+	}
+	e := c.Errors[0]
+	if e.Name != "UserNotFound" || e.Status != 500 || e.Kind != errorutil.KindInternal || e.Delay != Duration(60e9) {
+		t.Errorf("Parse: got %+v", e)
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	tests := []struct {
+		entry  string
+		kind   errorutil.Kind
+		status int
+	}{
+		{`{"code": "foo", "message": "foo", "kind": "not_found"}`, errorutil.KindNotFound, 404},
+		{`{"code": "foo", "message": "foo", "kind": "not_found", "status": 410}`, errorutil.KindNotFound, 410},
+		{`{"code": "foo", "message": "foo", "status": 429}`, errorutil.KindRateLimited, 429},
+	}
+	for _, tt := range tests {
+		c, err := Parse([]byte(`{"package": "foo", "errors": [` + tt.entry + `]}`))
+		if err != nil {
+			t.Errorf("Parse(%s): unexpected error %v", tt.entry, err)
+			continue
+		}
+		if e := c.Errors[0]; e.Kind != tt.kind || e.Status != tt.status {
+			t.Errorf("Parse(%s): got kind %q, status %d", tt.entry, e.Kind, e.Status)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		catalog string
+		err     string
+	}{
+		{`foo`, "catalog: invalid character"},
+		{`{"package": "foo-bar"}`, "invalid package name"},
+		{`{"package": "foo", "errors": [{"code": "", "message": "foo"}]}`, "empty error code"},
+		{`{"package": "foo", "errors": [{"code": "foo", "message": "foo"}, {"code": "foo", "message": "foo"}]}`, "duplicate error code"},
+		{`{"package": "foo", "errors": [{"code": "foo.bar", "message": "foo"}, {"code": "foo_bar", "message": "foo"}]}`, "duplicate name"},
+		{`{"package": "foo", "errors": [{"code": "404", "message": "foo"}]}`, "invalid name"},
+		{`{"package": "foo", "errors": [{"code": "foo", "name": "foo", "message": "foo"}]}`, "invalid name"},
+		{`{"package": "foo", "errors": [{"code": "foo", "status": 200, "message": "foo"}]}`, "invalid error status code"},
+		{`{"package": "foo", "errors": [{"code": "foo", "kind": "not_found", "status": 409, "message": "foo"}]}`, "does not match status code"},
+		{`{"package": "foo", "errors": [{"code": "foo", "kind": "foo", "message": "foo"}]}`, "does not match status code"},
+		{`{"package": "foo", "errors": [{"code": "foo", "delay": "foo", "message": "foo"}]}`, "invalid duration"},
+		{`{"package": "foo", "errors": [{"code": "foo", "delay": "-1s", "message": "foo"}]}`, "negative delay"},
+		{`{"package": "foo", "errors": [{"code": "foo"}]}`, "empty message"},
+		{`{"package": "foo", "errors": [{"code": "foo", "message": "foo", "fields": ["func"]}]}`, "invalid field"},
+		{`{"package": "foo", "errors": [{"code": "foo", "message": "foo", "fields": ["id", "id"]}]}`, "invalid field"},
+		{`{"package": "foo", "errors": [{"code": "foo", "message": "user {id} not found"}]}`, "undeclared field"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.catalog))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%s): got %v, want %q", tt.catalog, err, tt.err)
+		}
+	}
+}
+
+// TestGenerate checks that the generated example package is up to date.
+func TestGenerate(t *testing.T) {
+	data, err := ioutil.ReadFile("internal/example/errors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error %v", err)
+	}
+	for file, generate := range map[string]func() ([]byte, error){
+		"internal/example/errors_gen.go": c.GenerateGo,
+		"internal/example/errors.md":     c.GenerateMarkdown,
+	} {
+		got, err := generate()
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", file, err)
+		}
+		want, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is not up to date, run go generate", file)
+		}
+	}
+}
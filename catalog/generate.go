@@ -0,0 +1,195 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var funcs = template.FuncMap{
+	"quote": func(s string) string { return fmt.Sprintf("%q", s) },
+	"params": func(fields []string) string {
+		if len(fields) == 0 {
+			return ""
+		}
+		return strings.Join(fields, ", ") + " string"
+	},
+	"fields": func(fields []string) string {
+		if len(fields) == 0 {
+			return "nil"
+		}
+		pairs := make([]string, len(fields))
+		for i, f := range fields {
+			pairs[i] = fmt.Sprintf("%q: %s", f, f)
+		}
+		return "map[string]string{" + strings.Join(pairs, ", ") + "}"
+	},
+	"delay": func(d Duration) string {
+		switch {
+		case d == 0:
+			return "0"
+		case time.Duration(d)%time.Second == 0:
+			return fmt.Sprintf("%d * time.Second", time.Duration(d)/time.Second)
+		default:
+			return fmt.Sprintf("%d", int64(d))
+		}
+	},
+	"duration": func(d Duration) string {
+		if d == 0 {
+			return ""
+		}
+		return time.Duration(d).String()
+	},
+	"statusText": http.StatusText,
+	"comment": func(s string) string {
+		return strings.Replace(strings.TrimSpace(s), "\n", "\n// ", -1)
+	},
+	"cell": func(s string) string {
+		return strings.Replace(strings.Replace(strings.TrimSpace(s), "|", "\\|", -1), "\n", " ", -1)
+	},
+}
+
+var goTemplate = template.Must(template.New("go").Funcs(funcs).Parse(`// Code generated by errorutilgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Error codes.
+const (
+{{- range .Errors}}
+	{{- if .Description}}
+	// Code{{.Name}}: {{comment .Description}}
+	{{- end}}
+	Code{{.Name}} = {{quote .Code}}
+{{- end}}
+)
+
+var (
+{{- range .Errors}}
+	err{{.Name}} = &catalogError{code: Code{{.Name}}, status: {{.Status}}, retryable: {{.Retryable}}, delay: {{delay .Delay}}, message: {{quote .Message}}}
+{{- end}}
+)
+
+// Sentinel errors, to be compared with errors.Is.
+var (
+{{- range .Errors}}
+	Err{{.Name}} = err{{.Name}}.with(nil, nil)
+{{- end}}
+)
+{{range .Errors}}
+// New{{.Name}}Error returns a {{quote .Code}} error.
+func New{{.Name}}Error({{params .Fields}}) error {
+	return err{{.Name}}.with(nil, {{fields .Fields}})
+}
+
+// {{.Name}}Error marks an error as {{quote .Code}}. It returns nil if the error is nil.
+func {{.Name}}Error(err error{{if .Fields}}, {{params .Fields}}{{end}}) error {
+	if err == nil {
+		return nil
+	}
+	return err{{.Name}}.with(err, {{fields .Fields}})
+}
+{{end}}
+// catalogError is an error declared in the error catalog.
+// It implements the errorutil interfaces (Coder, HTTPStatusCodeEr, Retryabler, Delayer, PublicMessager).
+type catalogError struct {
+	err       error
+	code      string
+	status    int
+	retryable bool
+	delay     time.Duration
+	message   string
+	fields    map[string]string
+}
+
+func (e *catalogError) with(err error, fields map[string]string) error {
+	c := *e
+	c.fields = fields
+	if err == nil {
+		err = errors.New(c.PublicMessage())
+	}
+	c.err = err
+	return &c
+}
+
+func (e *catalogError) Error() string {
+	return e.err.Error()
+}
+
+func (e *catalogError) Cause() error {
+	return e.err
+}
+
+func (e *catalogError) Is(target error) bool {
+	t, ok := target.(*catalogError)
+	return ok && t.code == e.code
+}
+
+func (e *catalogError) Code() string {
+	return e.code
+}
+
+func (e *catalogError) HTTPStatusCode() int {
+	return e.status
+}
+
+func (e *catalogError) Retryable() bool {
+	return e.retryable
+}
+
+func (e *catalogError) Delay() time.Duration {
+	return e.delay
+}
+
+func (e *catalogError) PublicMessage() string {
+	if len(e.fields) == 0 {
+		return e.message
+	}
+	// placeholders are replaced in a single pass, field values are not scanned for other placeholders
+	pairs := make([]string, 0, 2*len(e.fields))
+	for k, v := range e.fields {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(e.message)
+}
+
+func (e *catalogError) Fields() map[string]string {
+	return e.fields
+}
+`))
+
+// GenerateGo returns the Go source code of the catalog.
+func (c *Catalog) GenerateGo() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := goTemplate.Execute(&buf, c); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(funcs).Parse(`# Errors of package {{.Package}}
+
+| Code | Kind | Status | Retryable | Delay | Message | Description |
+|------|------|--------|-----------|-------|---------|-------------|
+{{- range .Errors}}
+| ` + "`{{.Code}}`" + ` | {{.Kind}} | {{.Status}} {{statusText .Status}} | {{if .Retryable}}yes{{else}}no{{end}} | {{duration .Delay}} | {{cell .Message}} | {{cell .Description}} |
+{{- end}}
+`))
+
+// GenerateMarkdown returns a Markdown reference of the errors of the catalog.
+func (c *Catalog) GenerateMarkdown() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := markdownTemplate.Execute(&buf, c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
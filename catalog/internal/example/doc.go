@@ -0,0 +1,4 @@
+// Package example is generated from an error catalog, to test the generated code.
+package example
+
+//go:generate go run ../../../cmd/errorutilgen -out errors_gen.go -doc errors.md errors.json
@@ -0,0 +1,138 @@
+// Code generated by errorutilgen. DO NOT EDIT.
+
+package example
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Error codes.
+const (
+	// CodeUserNotFound: The user does not exist.
+	CodeUserNotFound = "user.not_found"
+	CodeUserExists   = "user.exists"
+	// CodeDbUnavailable: The database is unavailable.
+	// Retry later.
+	CodeDbUnavailable = "db.unavailable"
+)
+
+var (
+	errUserNotFound  = &catalogError{code: CodeUserNotFound, status: 404, retryable: false, delay: 0, message: "user {id} not found"}
+	errUserExists    = &catalogError{code: CodeUserExists, status: 409, retryable: false, delay: 0, message: "user {name} already exists in {org}"}
+	errDbUnavailable = &catalogError{code: CodeDbUnavailable, status: 503, retryable: true, delay: 30 * time.Second, message: "service temporarily unavailable"}
+)
+
+// Sentinel errors, to be compared with errors.Is.
+var (
+	ErrUserNotFound  = errUserNotFound.with(nil, nil)
+	ErrUserExists    = errUserExists.with(nil, nil)
+	ErrDbUnavailable = errDbUnavailable.with(nil, nil)
+)
+
+// NewUserNotFoundError returns a "user.not_found" error.
+func NewUserNotFoundError(id string) error {
+	return errUserNotFound.with(nil, map[string]string{"id": id})
+}
+
+// UserNotFoundError marks an error as "user.not_found". It returns nil if the error is nil.
+func UserNotFoundError(err error, id string) error {
+	if err == nil {
+		return nil
+	}
+	return errUserNotFound.with(err, map[string]string{"id": id})
+}
+
+// NewUserExistsError returns a "user.exists" error.
+func NewUserExistsError(name, org string) error {
+	return errUserExists.with(nil, map[string]string{"name": name, "org": org})
+}
+
+// UserExistsError marks an error as "user.exists". It returns nil if the error is nil.
+func UserExistsError(err error, name, org string) error {
+	if err == nil {
+		return nil
+	}
+	return errUserExists.with(err, map[string]string{"name": name, "org": org})
+}
+
+// NewDbUnavailableError returns a "db.unavailable" error.
+func NewDbUnavailableError() error {
+	return errDbUnavailable.with(nil, nil)
+}
+
+// DbUnavailableError marks an error as "db.unavailable". It returns nil if the error is nil.
+func DbUnavailableError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errDbUnavailable.with(err, nil)
+}
+
+// catalogError is an error declared in the error catalog.
+// It implements the errorutil interfaces (Coder, HTTPStatusCodeEr, Retryabler, Delayer, PublicMessager).
+type catalogError struct {
+	err       error
+	code      string
+	status    int
+	retryable bool
+	delay     time.Duration
+	message   string
+	fields    map[string]string
+}
+
+func (e *catalogError) with(err error, fields map[string]string) error {
+	c := *e
+	c.fields = fields
+	if err == nil {
+		err = errors.New(c.PublicMessage())
+	}
+	c.err = err
+	return &c
+}
+
+func (e *catalogError) Error() string {
+	return e.err.Error()
+}
+
+func (e *catalogError) Cause() error {
+	return e.err
+}
+
+func (e *catalogError) Is(target error) bool {
+	t, ok := target.(*catalogError)
+	return ok && t.code == e.code
+}
+
+func (e *catalogError) Code() string {
+	return e.code
+}
+
+func (e *catalogError) HTTPStatusCode() int {
+	return e.status
+}
+
+func (e *catalogError) Retryable() bool {
+	return e.retryable
+}
+
+func (e *catalogError) Delay() time.Duration {
+	return e.delay
+}
+
+func (e *catalogError) PublicMessage() string {
+	if len(e.fields) == 0 {
+		return e.message
+	}
+	// placeholders are replaced in a single pass, field values are not scanned for other placeholders
+	pairs := make([]string, 0, 2*len(e.fields))
+	for k, v := range e.fields {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(e.message)
+}
+
+func (e *catalogError) Fields() map[string]string {
+	return e.fields
+}
@@ -0,0 +1,55 @@
+package example
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	oerrors "github.com/objenious/errors"
+	"github.com/objenious/errorutil"
+	"github.com/objenious/errorutil/errorutiltest"
+)
+
+func TestGeneratedErrors(t *testing.T) {
+	err := NewUserNotFoundError("42")
+	errorutiltest.AssertStatus(t, err, http.StatusNotFound)
+	errorutiltest.AssertNotRetryable(t, err)
+	errorutiltest.AssertCode(t, err, "user.not_found")
+	if msg, ok := errorutil.PublicMessage(err); msg != "user 42 not found" || !ok {
+		t.Errorf("PublicMessage: got %q, %v", msg, ok)
+	}
+	if err.Error() != "user 42 not found" {
+		t.Errorf("Error: got %q", err.Error())
+	}
+	if !errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrUserExists) {
+		t.Errorf("errors.Is must match the sentinel error of the same code")
+	}
+
+	err = oerrors.Wrap(DbUnavailableError(errors.New("dial tcp: connection refused")), "unable to load user")
+	errorutiltest.AssertStatus(t, err, http.StatusServiceUnavailable)
+	errorutiltest.AssertRetryable(t, err)
+	errorutiltest.AssertDelay(t, err, 30*time.Second)
+	errorutiltest.AssertCode(t, err, "db.unavailable")
+	if msg, _ := errorutil.PublicMessage(err); msg != "service temporarily unavailable" {
+		t.Errorf("PublicMessage: got %q", msg)
+	}
+	if err.Error() != "unable to load user: dial tcp: connection refused" {
+		t.Errorf("Error: got %q", err.Error())
+	}
+
+	errorutiltest.AssertKind(t, NewUserExistsError("foo", "bar"), errorutil.KindConflict)
+	errorutiltest.AssertStatus(t, NewUserExistsError("foo", "bar"), http.StatusConflict)
+	if UserExistsError(nil, "foo", "bar") != nil {
+		t.Errorf("UserExistsError(nil) must return nil")
+	}
+	if msg, _ := errorutil.PublicMessage(NewUserExistsError("foo", "bar")); msg != "user foo already exists in bar" {
+		t.Errorf("PublicMessage: got %q", msg)
+	}
+	// field values must not be substituted, whatever the map iteration order
+	for i := 0; i < 20; i++ {
+		if msg, _ := errorutil.PublicMessage(NewUserExistsError("{org}", "bar")); msg != "user {org} already exists in bar" {
+			t.Fatalf("PublicMessage: got %q", msg)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Package chaos injects faults (errors, latency, HTTP status codes) into functions, HTTP transports and readers,
+// to test the resilience of code using errorutil.
+//
+// Faults are injected at configured probabilities, using a seeded random number generator so that runs are reproducible,
+// or on specific attempts.
+//
+// An Injector can be attached to a context, to enable fault injection per request :
+//
+//	ctx = chaos.NewContext(ctx, chaos.New(42, chaos.Fault{Probability: 0.1, Err: chaos.ErrRetryable}))
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/objenious/errorutil"
+)
+
+var (
+	// ErrRetryable is a retryable error, to be injected.
+	ErrRetryable = errorutil.NewRetryableError("chaos: injected retryable error")
+	// ErrNotRetryable is an error explicitly marked as not retryable, to be injected.
+	ErrNotRetryable = errorutil.NotRetryableError(errors.New("chaos: injected error"))
+)
+
+// Delayed returns a retryable error with a delay, to be injected.
+func Delayed(delay time.Duration) error {
+	return errorutil.WithDelay(errorutil.NewRetryableError("chaos: injected delayed error"), delay)
+}
+
+// Fault defines what is injected, and when.
+type Fault struct {
+	// Probability of injecting the fault on each attempt, between 0 and 1. Ignored if Attempts is set.
+	Probability float64
+	// Attempts, if set, lists the attempts (starting at 1) the fault is injected on.
+	Attempts []int
+
+	// Latency is waited before calling the wrapped function, transport or reader.
+	Latency time.Duration
+	// Err is returned instead of calling the wrapped function, transport or reader.
+	Err error
+	// Status is returned instead of calling the wrapped function or transport : a function returns the error
+	// built by errorutil.HTTPError, a transport returns a response with this status code. Ignored by readers.
+	Status int
+}
+
+func (f Fault) applies(attempt int, rng *rand.Rand) bool {
+	if len(f.Attempts) > 0 {
+		for _, a := range f.Attempts {
+			if a == attempt {
+				return true
+			}
+		}
+		return false
+	}
+	// always draw a number, so that the sequence does not depend on previous faults
+	return rng.Float64() < f.Probability
+}
+
+// Injector injects faults. Each call to a wrapped function, transport or reader is an attempt,
+// on which the first applicable fault is injected.
+//
+// An Injector is safe for concurrent use, but attempts are only reproducible if calls are sequential.
+type Injector struct {
+	faults []Fault
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	attempt int
+}
+
+// New returns an Injector, whose random number generator is initialized with seed.
+func New(seed int64, faults ...Fault) *Injector {
+	return &Injector{faults: faults, rng: rand.New(rand.NewSource(seed))}
+}
+
+// next returns the fault to inject on the next attempt.
+func (in *Injector) next() (Fault, bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.attempt++
+	var fault Fault
+	var found bool
+	for _, f := range in.faults {
+		if f.applies(in.attempt, in.rng) && !found {
+			fault, found = f, true
+		}
+	}
+	return fault, found
+}
+
+// inject waits for the latency of the fault, then returns whether the call should be replaced by the fault.
+func inject(ctx context.Context, f Fault) (bool, error) {
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+	return f.Err != nil || f.Status != 0, f.Err
+}
+
+// Func wraps a function, injecting faults.
+func (in *Injector) Func(fn func() error) func() error {
+	return func() error {
+		f, ok := in.next()
+		if !ok {
+			return fn()
+		}
+		if replaced, err := inject(context.Background(), f); replaced {
+			if err == nil {
+				err = errorutil.HTTPError(&http.Response{StatusCode: f.Status})
+			}
+			return err
+		}
+		return fn()
+	}
+}
+
+// RoundTripper wraps a http.RoundTripper, injecting faults. If rt is nil, http.DefaultTransport is used.
+func (in *Injector) RoundTripper(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &roundTripper{rt: rt, injector: func(*http.Request) *Injector { return in }}
+}
+
+// Reader wraps a io.Reader, injecting faults. Each call to Read is an attempt.
+func (in *Injector) Reader(r io.Reader) io.Reader {
+	return &reader{r: r, in: in}
+}
+
+type roundTripper struct {
+	rt       http.RoundTripper
+	injector func(*http.Request) *Injector
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	in := rt.injector(req)
+	if in == nil {
+		return rt.rt.RoundTrip(req)
+	}
+	f, ok := in.next()
+	if !ok {
+		return rt.rt.RoundTrip(req)
+	}
+	replaced, err := inject(req.Context(), f)
+	if !replaced {
+		return rt.rt.RoundTrip(req)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:     http.StatusText(f.Status),
+		StatusCode: f.Status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}, nil
+}
+
+type reader struct {
+	r  io.Reader
+	in *Injector
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	f, ok := r.in.next()
+	if !ok {
+		return r.r.Read(p)
+	}
+	f.Status = 0
+	if replaced, err := inject(context.Background(), f); replaced {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying an Injector.
+func NewContext(ctx context.Context, in *Injector) context.Context {
+	return context.WithValue(ctx, contextKey{}, in)
+}
+
+// FromContext returns the Injector carried by ctx, or nil.
+func FromContext(ctx context.Context) *Injector {
+	in, _ := ctx.Value(contextKey{}).(*Injector)
+	return in
+}
+
+// Transport wraps a http.RoundTripper, injecting faults using the Injector carried by the request context, if any.
+// If rt is nil, http.DefaultTransport is used.
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &roundTripper{rt: rt, injector: func(req *http.Request) *Injector { return FromContext(req.Context()) }}
+}
+
+// Func wraps a function, injecting faults using the Injector carried by ctx, if any.
+func Func(ctx context.Context, fn func() error) func() error {
+	if in := FromContext(ctx); in != nil {
+		return in.Func(fn)
+	}
+	return fn
+}
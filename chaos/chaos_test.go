@@ -0,0 +1,166 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/objenious/errorutil"
+)
+
+func results(fn func() error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = fn()
+	}
+	return errs
+}
+
+func TestFuncProbability(t *testing.T) {
+	ok := func() error { return nil }
+	a := results(New(42, Fault{Probability: 0.5, Err: ErrRetryable}).Func(ok), 100)
+	b := results(New(42, Fault{Probability: 0.5, Err: ErrRetryable}).Func(ok), 100)
+	var injected int
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("attempt %d: injectors with the same seed must inject the same faults", i+1)
+		}
+		if a[i] != nil {
+			injected++
+		}
+	}
+	if injected < 30 || injected > 70 {
+		t.Errorf("got %d faults injected, want about 50", injected)
+	}
+
+	for _, err := range results(New(42, Fault{Probability: 0, Err: ErrRetryable}).Func(ok), 100) {
+		if err != nil {
+			t.Fatalf("faults with a 0 probability must not be injected")
+		}
+	}
+}
+
+func TestFuncAttempts(t *testing.T) {
+	var calls int
+	fn := New(1,
+		Fault{Attempts: []int{1}, Err: ErrNotRetryable},
+		Fault{Attempts: []int{2}, Err: Delayed(time.Minute)},
+		Fault{Attempts: []int{3}, Status: http.StatusServiceUnavailable},
+		Fault{Attempts: []int{4}, Latency: 10 * time.Millisecond},
+	).Func(func() error {
+		calls++
+		return nil
+	})
+	errs := results(fn, 5)
+	if !errorutil.IsNotRetryable(errs[0]) {
+		t.Errorf("attempt 1: got %v, want a not retryable error", errs[0])
+	}
+	if !errorutil.IsRetryable(errs[1]) || errorutil.Delay(errs[1]) != time.Minute {
+		t.Errorf("attempt 2: got %v, want a delayed error", errs[1])
+	}
+	if !errorutil.IsRetryable(errs[2]) || errorutil.HTTPStatusCode(errs[2]) != http.StatusServiceUnavailable {
+		t.Errorf("attempt 3: got %v, want a 503 error", errs[2])
+	}
+	if errs[3] != nil || errs[4] != nil {
+		t.Errorf("attempts 4 and 5: got %v, %v, want no error", errs[3], errs[4])
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+}
+
+func TestRoundTripper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	in := New(1,
+		Fault{Attempts: []int{1}, Status: http.StatusTooManyRequests},
+		Fault{Attempts: []int{2}, Err: ErrRetryable},
+	)
+	client := &http.Client{Transport: in.RoundTripper(nil)}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("attempt 1: got %v, %v, want a 429 response", resp, err)
+	}
+	resp.Body.Close()
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrRetryable) {
+		t.Errorf("attempt 2: got %v, want ErrRetryable", err)
+	}
+	resp, err = client.Get(srv.URL)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("attempt 3: got %v, %v, want a 200 response", resp, err)
+	}
+	resp.Body.Close()
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("without injector: got %v, %v, want a 200 response", resp, err)
+	}
+	resp.Body.Close()
+
+	ctx := NewContext(context.Background(), New(1, Fault{Probability: 1, Status: http.StatusBadGateway}))
+	resp, err = client.Do(req.WithContext(ctx))
+	if err != nil || resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("with injector: got %v, %v, want a 502 response", resp, err)
+	}
+	resp.Body.Close()
+}
+
+func TestReader(t *testing.T) {
+	r := New(1, Fault{Attempts: []int{2}, Err: ErrRetryable}).Reader(strings.NewReader("foo"))
+	b := make([]byte, 1)
+	if n, err := r.Read(b); n != 1 || err != nil {
+		t.Errorf("attempt 1: got %d, %v", n, err)
+	}
+	if _, err := r.Read(b); err != ErrRetryable {
+		t.Errorf("attempt 2: got %v, want ErrRetryable", err)
+	}
+	if rest, err := ioutil.ReadAll(r); string(rest) != "oo" || err != nil {
+		t.Errorf("got %q, %v, want \"oo\"", rest, err)
+	}
+}
+
+func TestFuncContext(t *testing.T) {
+	ok := func() error { return nil }
+	if err := Func(context.Background(), ok)(); err != nil {
+		t.Errorf("without injector: got %v", err)
+	}
+	ctx := NewContext(context.Background(), New(1, Fault{Probability: 1, Err: ErrRetryable}))
+	if err := Func(ctx, ok)(); err != ErrRetryable {
+		t.Errorf("with injector: got %v, want ErrRetryable", err)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	// injected errors may be tagged again by the code under test
+	if err := errorutil.WithDelay(ErrRetryable, time.Second); !errors.Is(err, ErrRetryable) {
+		t.Errorf("errors.Is(%v, ErrRetryable): got false, want true", err)
+	}
+	if err := errorutil.WithCode(ErrNotRetryable, "foo"); !errors.Is(err, ErrNotRetryable) {
+		t.Errorf("errors.Is(%v, ErrNotRetryable): got false, want true", err)
+	}
+}
+
+func ExampleTransport() {
+	client := &http.Client{Transport: Transport(nil)}
+
+	// in a staging handler, enable fault injection for this request only
+	ctx := NewContext(context.Background(), New(time.Now().UnixNano(),
+		Fault{Probability: 0.2, Status: http.StatusServiceUnavailable},
+		Fault{Probability: 0.1, Latency: time.Second},
+	))
+	req, _ := http.NewRequest(http.MethodGet, "http://www.example.com", nil)
+	client.Do(req.WithContext(ctx))
+}
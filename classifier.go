@@ -0,0 +1,41 @@
+package errorutil
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Classifier returns the tags of errors that do not implement the errorutil interfaces, such as database driver errors.
+// It is called for each error of a chain, and returns nil for errors it does not know.
+type Classifier func(err error) []TagOption
+
+var (
+	classifiersMu sync.Mutex
+	classifiers   atomic.Value // []Classifier
+)
+
+// RegisterClassifier adds a Classifier used by all inspection functions (IsRetryable, HTTPStatusCode, Inspect...).
+// Errors implementing the errorutil interfaces take precedence over classifiers.
+//
+// It is usually called from the init function of a package such as sqlutil.
+func RegisterClassifier(c Classifier) {
+	classifiersMu.Lock()
+	defer classifiersMu.Unlock()
+	cs, _ := classifiers.Load().([]Classifier)
+	classifiers.Store(append(cs[:len(cs):len(cs)], c))
+}
+
+// classify returns a taggedError wrapping err with the tags returned by the first matching classifier, or nil.
+func classify(err error) *taggedError {
+	cs, _ := classifiers.Load().([]Classifier)
+	for _, c := range cs {
+		if opts := c(err); len(opts) > 0 {
+			t := &taggedError{err: err}
+			for _, opt := range opts {
+				opt(t)
+			}
+			return t
+		}
+	}
+	return nil
+}
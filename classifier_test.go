@@ -0,0 +1,48 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	oerrors "github.com/objenious/errors"
+)
+
+// driverError is only known by the classifier registered in init.
+type driverError string
+
+func (err driverError) Error() string { return string(err) }
+
+func init() {
+	RegisterClassifier(func(err error) []TagOption {
+		if err == driverError("timeout") {
+			return []TagOption{Status(http.StatusGatewayTimeout), Retryable(), After(time.Second), Coded("driver.timeout")}
+		}
+		return nil
+	})
+}
+
+func TestClassifier(t *testing.T) {
+	err := oerrors.Wrap(driverError("timeout"), "query")
+	if HTTPStatusCode(err) != http.StatusGatewayTimeout || !IsRetryable(err) || IsNotRetryable(err) || Delay(err) != time.Second || Code(err) != "driver.timeout" {
+		t.Errorf("classified error: got status %d, retryable %v, delay %v, code %q", HTTPStatusCode(err), IsRetryable(err), Delay(err), Code(err))
+	}
+	a := Inspect(err)
+	if a.Status != http.StatusGatewayTimeout || !a.Retryable || a.Delay != time.Second || a.Code != "driver.timeout" {
+		t.Errorf("Inspect: got %+v", a)
+	}
+
+	// tags set on the error take precedence
+	err = NotRetryableError(driverError("timeout"))
+	if IsRetryable(err) || HTTPStatusCode(err) != http.StatusInternalServerError {
+		t.Errorf("tags must take precedence over classifiers, got retryable %v, status %d", IsRetryable(err), HTTPStatusCode(err))
+	}
+
+	if err := driverError("other"); HTTPStatusCode(err) != http.StatusInternalServerError || IsRetryable(err) {
+		t.Errorf("unknown errors must not be classified")
+	}
+	if HTTPStatusCode(errors.New("timeout")) != http.StatusInternalServerError {
+		t.Errorf("unknown errors must not be classified")
+	}
+}
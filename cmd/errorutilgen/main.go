@@ -0,0 +1,64 @@
+// Command errorutilgen generates Go constructors and a Markdown reference from an error catalog
+// (see package github.com/objenious/errorutil/catalog).
+//
+// Usage :
+//
+//	errorutilgen -out errors_gen.go [-doc errors.md] catalog.json
+//
+// It is typically used with go generate :
+//
+//	//go:generate errorutilgen -out errors_gen.go -doc errors.md errors.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/objenious/errorutil/catalog"
+)
+
+func main() {
+	out := flag.String("out", "", "Go output file (required)")
+	doc := flag.String("doc", "", "Markdown output file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: errorutilgen -out errors_gen.go [-doc errors.md] catalog.json\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *out == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := generate(flag.Arg(0), *out, *doc); err != nil {
+		fmt.Fprintf(os.Stderr, "errorutilgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func generate(in, out, doc string) error {
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	c, err := catalog.Parse(data)
+	if err != nil {
+		return err
+	}
+	src, err := c.GenerateGo()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(out, src, 0o644); err != nil {
+		return err
+	}
+	if doc == "" {
+		return nil
+	}
+	md, err := c.GenerateMarkdown()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(doc, md, 0o644)
+}
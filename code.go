@@ -0,0 +1,112 @@
+package errorutil
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Coder defines errors carrying a stable, machine-readable code, such as "user.not_found".
+type Coder interface {
+	Code() string
+}
+
+// WithCode attaches a machine-readable code to an error. It returns nil if the error is nil.
+func WithCode(err error, code string) error {
+	if err == nil {
+		return nil
+	}
+	if code == "" {
+		return err
+	}
+	t := tag(err)
+	t.code = code
+	return t
+}
+
+// Code returns the machine-readable code of an error (i.e. implements Coder).
+//
+// If the error is nil or does not implement Coder, an empty string is returned.
+func Code(err error) string {
+	type causer interface {
+		Cause() error
+	}
+
+	for err != nil {
+		if t, ok := err.(*taggedError); ok {
+			if t.code != "" {
+				return t.code
+			}
+			err = t.err
+			continue
+		}
+		if coder, ok := err.(Coder); ok {
+			if code := coder.Code(); code != "" {
+				return code
+			}
+		}
+		if t := classify(err); t != nil && t.code != "" {
+			return t.code
+		}
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return ""
+}
+
+// CodeRegistry keeps track of the codes used by an application, ensuring that each code is declared only once.
+//
+// The zero value is ready to use.
+type CodeRegistry struct {
+	mu    sync.Mutex
+	codes map[string]string
+}
+
+// Register declares a code, with a short description. An error is returned if the code
+// is empty or has already been registered.
+func (r *CodeRegistry) Register(code, description string) error {
+	if code == "" {
+		return fmt.Errorf("errorutil: empty error code")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.codes[code]; ok {
+		return fmt.Errorf("errorutil: duplicate error code %q", code)
+	}
+	if r.codes == nil {
+		r.codes = map[string]string{}
+	}
+	r.codes[code] = description
+	return nil
+}
+
+// MustRegister is like Register but panics if the code cannot be registered.
+// It simplifies declaring codes in package level variables.
+func (r *CodeRegistry) MustRegister(code, description string) string {
+	if err := r.Register(code, description); err != nil {
+		panic(err)
+	}
+	return code
+}
+
+// Lookup returns the description of a registered code, and whether it has been registered.
+func (r *CodeRegistry) Lookup(code string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	description, ok := r.codes[code]
+	return description, ok
+}
+
+// Validate checks that the code of an error, if any, has been registered.
+func (r *CodeRegistry) Validate(err error) error {
+	code := Code(err)
+	if code == "" {
+		return nil
+	}
+	if _, ok := r.Lookup(code); !ok {
+		return fmt.Errorf("errorutil: unregistered error code %q", code)
+	}
+	return nil
+}
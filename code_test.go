@@ -0,0 +1,92 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{WithCode(nil, "foo"), ""},
+		{errors.New("foo"), ""},
+		{httpError(http.StatusNotFound), ""},
+
+		{WithCode(errors.New("foo"), "user.not_found"), "user.not_found"},
+		{WithCode(NotFoundError(errors.New("foo")), "user.not_found"), "user.not_found"},
+		{NotFoundError(WithCode(errors.New("foo"), "user.not_found")), "user.not_found"},
+		{oerrors.Wrap(WithCode(errors.New("foo"), "user.not_found"), "bar"), "user.not_found"},
+		{WithCode(WithCode(errors.New("foo"), "user.not_found"), "quota.exceeded"), "quota.exceeded"},
+		{WithCode(WithCode(errors.New("foo"), "user.not_found"), ""), "user.not_found"},
+	}
+	for _, tt := range tests {
+		got := Code(tt.err)
+		if got != tt.want {
+			t.Errorf("Code(%q): got: %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWithCode(t *testing.T) {
+	err := WithCode(ConflictError(errors.New("foo")), "user.exists")
+	if err.Error() != "foo" {
+		t.Errorf("WithCode must not change the error text, got %q", err.Error())
+	}
+	if HTTPStatusCode(err) != http.StatusConflict {
+		t.Errorf("WithCode must keep the status code, got %d", HTTPStatusCode(err))
+	}
+}
+
+func TestCodeRegistry(t *testing.T) {
+	var r CodeRegistry
+	if err := r.Register("user.not_found", "the user does not exist"); err != nil {
+		t.Errorf("Register: unexpected error %v", err)
+	}
+	if err := r.Register("user.not_found", "again"); err == nil {
+		t.Errorf("Register must fail on duplicate codes")
+	}
+	if err := r.Register("", "empty"); err == nil {
+		t.Errorf("Register must fail on empty codes")
+	}
+	if description, ok := r.Lookup("user.not_found"); !ok || description != "the user does not exist" {
+		t.Errorf("Lookup: got %q, %v", description, ok)
+	}
+	if err := r.Validate(WithCode(errors.New("foo"), "user.not_found")); err != nil {
+		t.Errorf("Validate: unexpected error %v", err)
+	}
+	if err := r.Validate(WithCode(errors.New("foo"), "quota.exceeded")); err == nil {
+		t.Errorf("Validate must fail on unregistered codes")
+	}
+	if err := r.Validate(errors.New("foo")); err != nil {
+		t.Errorf("Validate: unexpected error %v", err)
+	}
+}
+
+func TestCodeRegistryMustRegister(t *testing.T) {
+	var r CodeRegistry
+	if code := r.MustRegister("user.not_found", ""); code != "user.not_found" {
+		t.Errorf("MustRegister: got %q", code)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustRegister must panic on duplicate codes")
+		}
+	}()
+	r.MustRegister("user.not_found", "")
+}
+
+func ExampleWithCode() {
+	var codes CodeRegistry
+	codeUserNotFound := codes.MustRegister("user.not_found", "the user does not exist")
+
+	err := errors.New("sql: no rows in result set")
+	err = WithCode(NotFoundError(err), codeUserNotFound)
+	Code(err)           // returns "user.not_found"
+	HTTPStatusCode(err) // returns http.StatusNotFound
+}
@@ -19,9 +19,19 @@ func Delay(err error) time.Duration {
 	}
 
 	for err != nil {
+		if t, ok := err.(*taggedError); ok {
+			if t.mask&hasDelay != 0 {
+				return t.delay
+			}
+			err = t.err
+			continue
+		}
 		if delay, ok := err.(Delayer); ok {
 			return delay.Delay()
 		}
+		if t := classify(err); t != nil && t.mask&hasDelay != 0 {
+			return t.delay
+		}
 		cause, ok := err.(causer)
 		if !ok {
 			break
@@ -36,23 +46,13 @@ func WithDelay(err error, duration time.Duration) error {
 	if err == nil {
 		return nil
 	}
-	return &delayedError{error: err, duration: duration}
+	t := tag(err)
+	t.mask |= hasDelay
+	t.delay = duration
+	return t
 }
 
 // NewDelayedError returns a delayed error that formats as the given text and duration.
 func NewDelayedError(text string, duration time.Duration) error {
 	return WithDelay(errors.New(text), duration)
 }
-
-type delayedError struct {
-	error
-	duration time.Duration
-}
-
-func (err *delayedError) Delay() time.Duration {
-	return err.duration
-}
-
-func (err *delayedError) Cause() error {
-	return err.error
-}
@@ -0,0 +1,82 @@
+package errorutil
+
+import (
+	"fmt"
+	"time"
+)
+
+// Action is what a queue worker should do with a message once it has been processed.
+type Action int
+
+const (
+	// Ack acknowledges the message, it will not be delivered again.
+	Ack Action = iota
+	// Retry rejects the message, it should be delivered again after Decision.Delay.
+	Retry
+	// DeadLetter removes the message from the queue, and moves it to a dead-letter queue if any.
+	DeadLetter
+)
+
+func (a Action) String() string {
+	switch a {
+	case Ack:
+		return "ack"
+	case Retry:
+		return "retry"
+	case DeadLetter:
+		return "dead-letter"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// Decision is returned by Disposition.
+type Decision struct {
+	Action Action
+	// Delay before the message is delivered again, when Action is Retry.
+	Delay time.Duration
+	// Reason is a human readable explanation of the decision.
+	Reason string
+}
+
+// DispositionOptions configures Disposition.
+type DispositionOptions struct {
+	// MaxAttempts is the maximum number of attempts. Once reached, retryable errors are dead-lettered.
+	// If 0, retryable errors are always retried.
+	MaxAttempts int
+	// Backoff returns the delay before the next attempt, for retryable errors without a delay (see Delay).
+	// attempt starts at 1. If nil, messages are retried without delay.
+	Backoff func(attempt int) time.Duration
+}
+
+// Disposition decides what should be done with a queue message, based on the error returned by its processing
+// and the number of attempts so far (starting at 1) :
+//
+// If the error is nil, the message is acked.
+//
+// If the error is retryable, the message is retried after Delay(err) or, if the error has no delay, opts.Backoff(attempt),
+// until opts.MaxAttempts is reached.
+//
+// Otherwise (not retryable or 4xx status code), the message is dead-lettered.
+func Disposition(err error, attempt int, opts DispositionOptions) Decision {
+	if err == nil {
+		return Decision{Action: Ack, Reason: "success"}
+	}
+	if IsNotRetryable(err) {
+		return Decision{Action: DeadLetter, Reason: "not retryable: " + err.Error()}
+	}
+	if IsRetryable(err) {
+		if opts.MaxAttempts > 0 && attempt >= opts.MaxAttempts {
+			return Decision{Action: DeadLetter, Reason: fmt.Sprintf("max attempts (%d) reached: %s", opts.MaxAttempts, err.Error())}
+		}
+		delay := Delay(err)
+		if delay <= 0 && opts.Backoff != nil {
+			delay = opts.Backoff(attempt)
+		}
+		return Decision{Action: Retry, Delay: delay, Reason: "retryable: " + err.Error()}
+	}
+	if status := HTTPStatusCode(err); status >= 400 && status < 500 {
+		return Decision{Action: DeadLetter, Reason: fmt.Sprintf("client error (%d): %s", status, err.Error())}
+	}
+	return Decision{Action: DeadLetter, Reason: "not retryable: " + err.Error()}
+}
@@ -0,0 +1,65 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestDisposition(t *testing.T) {
+	opts := DispositionOptions{
+		MaxAttempts: 3,
+		Backoff: func(attempt int) time.Duration {
+			return time.Duration(attempt) * time.Second
+		},
+	}
+	tests := []struct {
+		err     error
+		attempt int
+		opts    DispositionOptions
+		action  Action
+		delay   time.Duration
+	}{
+		{nil, 1, opts, Ack, 0},
+
+		{errors.New("foo"), 1, opts, DeadLetter, 0},
+		{NotRetryableError(errors.New("foo")), 1, opts, DeadLetter, 0},
+		{NotRetryableError(httpError(http.StatusServiceUnavailable)), 1, opts, DeadLetter, 0},
+		{NotFoundError(errors.New("foo")), 1, opts, DeadLetter, 0},
+		{httpError(http.StatusBadRequest), 1, opts, DeadLetter, 0},
+
+		{RetryableError(errors.New("foo")), 1, opts, Retry, time.Second},
+		{oerrors.Wrap(RetryableError(errors.New("foo")), "bar"), 2, opts, Retry, 2 * time.Second},
+		{RetryableError(errors.New("foo")), 3, opts, DeadLetter, 0},
+		{RetryableError(errors.New("foo")), 3, DispositionOptions{}, Retry, 0},
+		{httpError(429), 1, opts, Retry, time.Second},
+		{WithDelay(RetryableError(errors.New("foo")), time.Minute), 1, opts, Retry, time.Minute},
+		{WithDelay(RetryableError(errors.New("foo")), time.Minute), 1, DispositionOptions{}, Retry, time.Minute},
+	}
+	for _, tt := range tests {
+		got := Disposition(tt.err, tt.attempt, tt.opts)
+		if got.Action != tt.action || got.Delay != tt.delay {
+			t.Errorf("Disposition(%q, %d): got %v %v, want %v %v", tt.err, tt.attempt, got.Action, got.Delay, tt.action, tt.delay)
+		}
+		if got.Reason == "" {
+			t.Errorf("Disposition(%q, %d): empty reason", tt.err, tt.attempt)
+		}
+	}
+}
+
+func ExampleDisposition() {
+	var attempt int
+	var err error // returned by the message handler
+	d := Disposition(err, attempt, DispositionOptions{MaxAttempts: 5})
+	switch d.Action {
+	case Ack:
+		// ack the message
+	case Retry:
+		// nack the message, and redeliver it after d.Delay
+	case DeadLetter:
+		// move the message to the dead-letter queue, d.Reason explains why
+	}
+}
@@ -40,16 +40,97 @@ Find the most appropriate status code for an error :
 
   w.WriteHeader(errorutil.HTTPStatusCode(err))
 
+Write an error response, propagating its classification to HTTPError on the client side :
+
+  errorutil.WriteError(w, err)
+
 Generate specific error types :
 
   err := errors.New("some error")
   err = errorutil.NotFoundError(err)
   w.WriteHeader(errorutil.HTTPStatusCode(err)) // returns http.StatusNotFound
+  err = errorutil.TooManyRequestsError(err, time.Minute) // retryable, after a minute
+  err = errorutil.WithHTTPStatus(err, http.StatusTeapot)
+
+The kind of an error is a coarser classification derived from its status code :
+
+  errorutil.KindOf(err) // returns errorutil.KindNotFound for a 404 or 410 status code
+
+Quota errors
+
+QuotaError carries the limit, remaining requests and reset time of a quota, propagated through rate limit headers :
+
+  errorutil.WriteError(w, errorutil.NewQuotaError("search", 100, reset))
+  q := errorutil.Quota(errorutil.HTTPError(resp))
+
+Version conflicts
+
+Conflict errors carry the current version of the resource, sent in the ETag header by WriteError :
+
+  err = errorutil.VersionConflictError(err, current, expected)
+  current, expected, ok := errorutil.Versions(err)
+
+Validation errors
+
+Collect field violations, rendered in the "errors" member of the problem written by WriteError :
+
+  v := errorutil.NewValidation()
+  v.Check(req.Email != "", "email", "required", "email is required", req.Email)
+  err := v.Err() // nil if there are no violations
+
+Inspecting errors
+
+Resolve all the attributes of an error in a single walk of its cause chain :
+
+  a := errorutil.Inspect(err)
+  a.Retryable // same as errorutil.IsRetryable(err)
+
+Set several attributes at once :
+
+  err = errorutil.Tag(err, errorutil.Retryable(), errorutil.After(30*time.Second), errorutil.Status(503), errorutil.Coded("db.unavailable"))
+
+Classifiers
+
+Errors that do not implement the errorutil interfaces, such as database driver errors, can be classified
+by a Classifier registered with RegisterClassifier (see sqlutil sub package for SQLSTATE codes,
+and gcputil sub package for Google Cloud API errors).
+
+Public messages
+
+Attach a message that is safe to return to API clients, while logs keep the full error text :
+
+  err = errorutil.WithPublicMessage(err, "user not found")
+  msg, _ := errorutil.PublicMessage(err) // returns "user not found"
+
+Messages can be translated by a Localizer (see i18n sub package), using the fields of the error :
+
+  err = errorutil.WithFields(err, map[string]string{"id": id})
+  errorutil.WriteLocalizedError(w, r, err, catalog)
+
+Error codes
+
+Attach a stable, machine-readable code :
+
+  err = errorutil.WithCode(errorutil.NotFoundError(err), "user.not_found")
+  errorutil.Code(err) // returns "user.not_found"
+
+Custom values
+
+Attach typed metadata, using unexported key types :
+
+  err = errorutil.WithValue(err, tenantKey{}, tenantID)
+  tenantID, ok := errorutil.Value[string](err, tenantKey{})
 
 Exponential backoff
 
 see backoffutil sub package
 
+Metrics
+
+Errors written, errors received and retries are reported to the Metrics set by SetMetrics (see expvarmetrics sub package) :
+
+  errorutil.SetMetrics(expvarmetrics.New("errors"))
+
 Notes
 
 errorutil is compatible with https://github.com/objenious/errors :
@@ -0,0 +1,117 @@
+// Package errorutiltest provides helpers to test code using errorutil :
+// assertions on the classification of errors, and functions returning scripted errors.
+package errorutiltest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/objenious/errorutil"
+)
+
+// AssertRetryable reports a test failure if the error is not retryable.
+func AssertRetryable(t testing.TB, err error) {
+	t.Helper()
+	if !errorutil.IsRetryable(err) {
+		t.Errorf("IsRetryable(%q): got false, want true", err)
+	}
+}
+
+// AssertNotRetryable reports a test failure if the error is not explicitly marked as not retryable.
+func AssertNotRetryable(t testing.TB, err error) {
+	t.Helper()
+	if !errorutil.IsNotRetryable(err) {
+		t.Errorf("IsNotRetryable(%q): got false, want true", err)
+	}
+}
+
+// AssertStatus reports a test failure if HTTPStatusCode does not return the expected status code.
+func AssertStatus(t testing.TB, err error, status int) {
+	t.Helper()
+	if got := errorutil.HTTPStatusCode(err); got != status {
+		t.Errorf("HTTPStatusCode(%q): got %d, want %d", err, got, status)
+	}
+}
+
+// AssertKind reports a test failure if KindOf does not return the expected kind.
+func AssertKind(t testing.TB, err error, kind errorutil.Kind) {
+	t.Helper()
+	if got := errorutil.KindOf(err); got != kind {
+		t.Errorf("KindOf(%q): got %q, want %q", err, got, kind)
+	}
+}
+
+// AssertDelay reports a test failure if Delay does not return the expected delay.
+func AssertDelay(t testing.TB, err error, delay time.Duration) {
+	t.Helper()
+	if got := errorutil.Delay(err); got != delay {
+		t.Errorf("Delay(%q): got %v, want %v", err, got, delay)
+	}
+}
+
+// AssertCode reports a test failure if Code does not return the expected code.
+func AssertCode(t testing.TB, err error, code string) {
+	t.Helper()
+	if got := errorutil.Code(err); got != code {
+		t.Errorf("Code(%q): got %q, want %q", err, got, code)
+	}
+}
+
+// AssertSameClassification reports a test failure if two errors are not classified the same way (see Diff).
+func AssertSameClassification(t testing.TB, got, want error) {
+	t.Helper()
+	if diff := Diff(got, want); diff != "" {
+		t.Errorf("classification mismatch (-got +want):\n%s", diff)
+	}
+}
+
+// Diff compares the classification of two errors (retryable, HTTP status code, delay, code and public message).
+// It returns an empty string if they are classified the same way, a description of the differences otherwise.
+// Error messages are not compared.
+func Diff(got, want error) string {
+	var b strings.Builder
+	diff := func(name string, got, want interface{}) {
+		if got != want {
+			fmt.Fprintf(&b, "%s: -%v +%v\n", name, got, want)
+		}
+	}
+	diff("IsNil", got == nil, want == nil)
+	diff("IsRetryable", errorutil.IsRetryable(got), errorutil.IsRetryable(want))
+	diff("IsNotRetryable", errorutil.IsNotRetryable(got), errorutil.IsNotRetryable(want))
+	diff("HTTPStatusCode", errorutil.HTTPStatusCode(got), errorutil.HTTPStatusCode(want))
+	diff("Delay", errorutil.Delay(got), errorutil.Delay(want))
+	diff("Code", fmt.Sprintf("%q", errorutil.Code(got)), fmt.Sprintf("%q", errorutil.Code(want)))
+	gotMsg, _ := errorutil.PublicMessage(got)
+	wantMsg, _ := errorutil.PublicMessage(want)
+	diff("PublicMessage", fmt.Sprintf("%q", gotMsg), fmt.Sprintf("%q", wantMsg))
+	return b.String()
+}
+
+// Flaky returns a function that returns err for the first n calls, then nil.
+// It is safe for concurrent use.
+func Flaky(n int, err error) func() error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return Sequence(errs...)
+}
+
+// Sequence returns a function that returns the given errors in order, one per call, then nil.
+// It is safe for concurrent use.
+func Sequence(errs ...error) func() error {
+	var mu sync.Mutex
+	var i int
+	return func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if i >= len(errs) {
+			return nil
+		}
+		i++
+		return errs[i-1]
+	}
+}
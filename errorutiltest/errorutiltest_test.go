@@ -0,0 +1,101 @@
+package errorutiltest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/objenious/errorutil"
+)
+
+// recorder records failures, to test assertions.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	err := errorutil.WithCode(errorutil.WithDelay(errorutil.NewRetryableError("foo"), time.Second), "db.unavailable")
+	tests := []struct {
+		name   string
+		assert func(t testing.TB)
+		fail   bool
+	}{
+		{"AssertRetryable", func(t testing.TB) { AssertRetryable(t, err) }, false},
+		{"AssertRetryable", func(t testing.TB) { AssertRetryable(t, errors.New("foo")) }, true},
+		{"AssertNotRetryable", func(t testing.TB) { AssertNotRetryable(t, errorutil.NotRetryableError(errors.New("foo"))) }, false},
+		{"AssertNotRetryable", func(t testing.TB) { AssertNotRetryable(t, errors.New("foo")) }, true},
+		{"AssertStatus", func(t testing.TB) { AssertStatus(t, errorutil.NotFoundError(errors.New("foo")), http.StatusNotFound) }, false},
+		{"AssertStatus", func(t testing.TB) { AssertStatus(t, errors.New("foo"), http.StatusNotFound) }, true},
+		{"AssertDelay", func(t testing.TB) { AssertDelay(t, err, time.Second) }, false},
+		{"AssertDelay", func(t testing.TB) { AssertDelay(t, err, time.Minute) }, true},
+		{"AssertKind", func(t testing.TB) { AssertKind(t, errorutil.NotFoundError(errors.New("foo")), errorutil.KindNotFound) }, false},
+		{"AssertKind", func(t testing.TB) { AssertKind(t, errors.New("foo"), errorutil.KindNotFound) }, true},
+		{"AssertCode", func(t testing.TB) { AssertCode(t, err, "db.unavailable") }, false},
+		{"AssertCode", func(t testing.TB) { AssertCode(t, err, "") }, true},
+		{"AssertSameClassification", func(t testing.TB) { AssertSameClassification(t, err, err) }, false},
+		{"AssertSameClassification", func(t testing.TB) { AssertSameClassification(t, err, errors.New("foo")) }, true},
+	}
+	for _, tt := range tests {
+		r := &recorder{TB: t}
+		tt.assert(r)
+		if failed := len(r.failures) > 0; failed != tt.fail {
+			t.Errorf("%s: got failures %q, want failure %v", tt.name, r.failures, tt.fail)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		got, want error
+		diff      string
+	}{
+		{nil, nil, ""},
+		{errors.New("foo"), errors.New("bar"), ""},
+		{errorutil.NotFoundError(errors.New("foo")), errorutil.NotFoundError(errors.New("bar")), ""},
+		{errors.New("foo"), nil, "IsNil: -false +true\nHTTPStatusCode: -500 +200\nPublicMessage: -\"Internal Server Error\" +\"\"\n"},
+		{errorutil.NewRetryableError("foo"), errorutil.NotRetryableError(errors.New("foo")), "IsRetryable: -true +false\nIsNotRetryable: -false +true\n"},
+		{errorutil.WithDelay(errors.New("foo"), time.Second), errors.New("foo"), "Delay: -1s +0s\n"},
+		{errorutil.WithCode(errors.New("foo"), "foo"), errors.New("foo"), "Code: -\"foo\" +\"\"\n"},
+	}
+	for _, tt := range tests {
+		if diff := Diff(tt.got, tt.want); diff != tt.diff {
+			t.Errorf("Diff(%q, %q): got %q, want %q", tt.got, tt.want, diff, tt.diff)
+		}
+	}
+}
+
+func TestFlaky(t *testing.T) {
+	err := errorutil.NewRetryableError("foo")
+	fn := Flaky(2, err)
+	for i, want := range []error{err, err, nil, nil} {
+		if got := fn(); got != want {
+			t.Errorf("call %d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestSequence(t *testing.T) {
+	err1, err2 := errorutil.NewRetryableError("foo"), errorutil.NotFoundError(errors.New("bar"))
+	fn := Sequence(err1, nil, err2)
+	for i, want := range []error{err1, nil, err2, nil} {
+		if got := fn(); got != want {
+			t.Errorf("call %d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func ExampleFlaky() {
+	fn := Flaky(2, errorutil.NewRetryableError("unavailable"))
+	fn() // returns the retryable error
+	fn() // returns the retryable error
+	fn() // returns nil
+}
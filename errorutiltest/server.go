@@ -0,0 +1,108 @@
+package errorutiltest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// Step defines the response to a request received by a ScriptedServer.
+type Step struct {
+	// Status is the status code of the response. Defaults to http.StatusOK.
+	Status int
+	// Header is added to the response headers (e.g. Retry-After).
+	Header http.Header
+	// Body is the response body.
+	Body string
+	// Latency is waited before responding.
+	Latency time.Duration
+	// Drop closes the connection without responding.
+	Drop bool
+}
+
+// Request is a request received by a ScriptedServer.
+type Request struct {
+	// Attempt is the index of the request, starting at 1.
+	Attempt int
+	Time    time.Time
+	Method  string
+	URL     string
+	Header  http.Header
+	Body    []byte
+}
+
+// ScriptedServer is a HTTP test server responding to each request with the next step of a script.
+// Once the script is exhausted, the last step is repeated.
+type ScriptedServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	steps    []Step
+	requests []Request
+}
+
+// NewScriptedServer starts a ScriptedServer. It should be closed once done.
+func NewScriptedServer(steps ...Step) *ScriptedServer {
+	s := &ScriptedServer{steps: steps}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Requests returns the requests received so far.
+func (s *ScriptedServer) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *ScriptedServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mu.Lock()
+	req := Request{
+		Attempt: len(s.requests) + 1,
+		Time:    time.Now(),
+		Method:  r.Method,
+		URL:     r.URL.String(),
+		Header:  r.Header.Clone(),
+		Body:    body,
+	}
+	s.requests = append(s.requests, req)
+	var step Step
+	switch {
+	case req.Attempt <= len(s.steps):
+		step = s.steps[req.Attempt-1]
+	case len(s.steps) > 0:
+		step = s.steps[len(s.steps)-1]
+	}
+	s.mu.Unlock()
+
+	if step.Latency > 0 {
+		select {
+		case <-time.After(step.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if step.Drop {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+	for key, values := range step.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	status := step.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(step.Body))
+}
@@ -0,0 +1,129 @@
+package errorutiltest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/objenious/errorutil"
+	"github.com/objenious/errorutil/backoffutil"
+)
+
+// client does not reuse connections, so that dropped connections are not retried by the transport.
+var client = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+func get(url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return errorutil.RetryableError(err)
+	}
+	defer resp.Body.Close()
+	return errorutil.HTTPError(resp)
+}
+
+func TestScriptedServerRetry(t *testing.T) {
+	srv := NewScriptedServer(
+		Step{Status: http.StatusServiceUnavailable},
+		Step{Drop: true},
+		Step{Status: http.StatusOK},
+	)
+	defer srv.Close()
+	if err := backoffutil.Retry(func() error { return get(srv.URL) }); err != nil {
+		t.Errorf("Retry: unexpected error %v", err)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("got %d requests, want 3", len(reqs))
+	}
+	for i := 1; i < len(reqs); i++ {
+		if reqs[i].Attempt != i+1 {
+			t.Errorf("got attempt %d, want %d", reqs[i].Attempt, i+1)
+		}
+		if reqs[i].Time.Sub(reqs[i-1].Time) < 100*time.Millisecond {
+			t.Errorf("attempt %d: the client must back off", reqs[i].Attempt)
+		}
+	}
+}
+
+func TestScriptedServerPermanentError(t *testing.T) {
+	srv := NewScriptedServer(Step{Status: http.StatusNotFound}, Step{Status: http.StatusOK})
+	defer srv.Close()
+	err := backoffutil.Retry(func() error { return get(srv.URL) })
+	AssertStatus(t, err, http.StatusNotFound)
+	if reqs := srv.Requests(); len(reqs) != 1 {
+		t.Errorf("got %d requests, want 1", len(reqs))
+	}
+}
+
+func TestScriptedServerDelay(t *testing.T) {
+	srv := NewScriptedServer(Step{
+		Status: http.StatusServiceUnavailable,
+		Header: http.Header{"Retry-After": {"120"}},
+	})
+	defer srv.Close()
+	err := get(srv.URL)
+	AssertRetryable(t, err)
+	AssertDelay(t, err, 2*time.Minute)
+}
+
+func TestScriptedServerRetryAfter(t *testing.T) {
+	srv := NewScriptedServer(
+		Step{Status: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"1"}}},
+		Step{Status: http.StatusOK},
+	)
+	defer srv.Close()
+	if err := backoffutil.Retry(func() error { return get(srv.URL) }); err != nil {
+		t.Errorf("Retry: unexpected error %v", err)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests, want 2", len(reqs))
+	}
+	// the backoff interval would be at most 750ms
+	if d := reqs[1].Time.Sub(reqs[0].Time); d < time.Second {
+		t.Errorf("the client must wait for the Retry-After delay, got %v", d)
+	}
+}
+
+func TestScriptedServerResponse(t *testing.T) {
+	srv := NewScriptedServer(Step{
+		Status:  http.StatusCreated,
+		Header:  http.Header{"X-Foo": {"bar"}},
+		Body:    "baz",
+		Latency: 20 * time.Millisecond,
+	})
+	defer srv.Close()
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		resp, err := http.Post(srv.URL+"/foo?bar=baz", "text/plain", strings.NewReader("qux"))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated || resp.Header.Get("X-Foo") != "bar" || string(body) != "baz" {
+			t.Errorf("got response %d %v %q", resp.StatusCode, resp.Header, body)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("the server must wait before responding, got %v", elapsed)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests, want 2", len(reqs))
+	}
+	if r := reqs[1]; r.Attempt != 2 || r.Method != http.MethodPost || r.URL != "/foo?bar=baz" || string(r.Body) != "qux" || r.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("got request %+v", r)
+	}
+}
+
+func ExampleNewScriptedServer() {
+	srv := NewScriptedServer(
+		Step{Status: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"1"}}},
+		Step{Status: http.StatusOK, Body: "ok"},
+	)
+	defer srv.Close()
+	// call srv.URL, then check srv.Requests()
+}
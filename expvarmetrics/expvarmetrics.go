@@ -0,0 +1,150 @@
+// Package expvarmetrics implements errorutil.Metrics on top of the expvar package.
+//
+//	errorutil.SetMetrics(expvarmetrics.New("errors"))
+//
+// Counters are then served as JSON by the /debug/vars handler :
+//
+//	{
+//	  "errors": {
+//	    "server": {"total": 3, "kind": {"not_found": 2, "internal": 1}, "status": {"4xx": 2, "5xx": 1}, "retryable": {"false": 2, "unknown": 1}, "code": {"user.not_found": 2}},
+//	    "client": {...},
+//	    "retry": {"total": 4, "kind": {...}, ..., "delay_seconds": 3.2, "delay": {"le=100ms": 0, "le=500ms": 1, ..., "le=+Inf": 4}}
+//	  }
+//	}
+//
+// Errors written by servers, errors built from responses and retried errors are counted independently by kind,
+// status class, retryability and code (see errorutil.ErrorLabels).
+// The retry delay histogram is cumulative : each bucket counts the delays lower than or equal to its bound.
+package expvarmetrics
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/objenious/errorutil"
+)
+
+// DefaultBuckets are the bounds of the retry delay histogram used by New.
+var DefaultBuckets = []time.Duration{
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// Metrics counts errors in expvar maps. It implements errorutil.Metrics and expvar.Var.
+type Metrics struct {
+	root    *expvar.Map
+	server  *counters
+	client  *counters
+	retry   *counters
+	delays  *expvar.Map
+	buckets []time.Duration
+}
+
+var _ errorutil.Metrics = (*Metrics)(nil)
+
+// New returns a Metrics published as the expvar variable named name, with the DefaultBuckets.
+// Like expvar.Publish, it panics if the name is already registered.
+func New(name string) *Metrics {
+	return NewWithBuckets(name, DefaultBuckets)
+}
+
+// NewWithBuckets is like New, with custom histogram buckets, in increasing order.
+func NewWithBuckets(name string, buckets []time.Duration) *Metrics {
+	m := newMetrics(buckets)
+	expvar.Publish(name, m)
+	return m
+}
+
+// newMetrics returns a Metrics that is not published.
+func newMetrics(buckets []time.Duration) *Metrics {
+	m := &Metrics{
+		root:    new(expvar.Map).Init(),
+		server:  newCounters(),
+		client:  newCounters(),
+		retry:   newCounters(),
+		delays:  new(expvar.Map).Init(),
+		buckets: buckets,
+	}
+	m.root.Set("server", m.server.root)
+	m.root.Set("client", m.client.root)
+	m.root.Set("retry", m.retry.root)
+	m.retry.root.AddFloat("delay_seconds", 0)
+	m.retry.root.Set("delay", m.delays)
+	for _, b := range buckets {
+		m.delays.Add(bucketKey(b), 0)
+	}
+	m.delays.Add("le=+Inf", 0)
+	return m
+}
+
+func bucketKey(b time.Duration) string {
+	return "le=" + b.String()
+}
+
+// String returns the counters as JSON.
+func (m *Metrics) String() string {
+	return m.root.String()
+}
+
+// ServerError counts an error written by a HTTP handler.
+func (m *Metrics) ServerError(err error) {
+	m.server.add(err)
+}
+
+// ClientError counts an error built from a HTTP response.
+func (m *Metrics) ClientError(err error) {
+	m.client.add(err)
+}
+
+// Retry counts the error of a retried attempt, and records its delay in the histogram.
+func (m *Metrics) Retry(err error, delay time.Duration) {
+	m.retry.add(err)
+	m.retry.root.AddFloat("delay_seconds", delay.Seconds())
+	for _, b := range m.buckets {
+		if delay <= b {
+			m.delays.Add(bucketKey(b), 1)
+		}
+	}
+	m.delays.Add("le=+Inf", 1)
+}
+
+// counters counts errors by kind, status class, retryability and code.
+type counters struct {
+	root      *expvar.Map
+	kind      *expvar.Map
+	status    *expvar.Map
+	retryable *expvar.Map
+	code      *expvar.Map
+}
+
+func newCounters() *counters {
+	c := &counters{
+		root:      new(expvar.Map).Init(),
+		kind:      new(expvar.Map).Init(),
+		status:    new(expvar.Map).Init(),
+		retryable: new(expvar.Map).Init(),
+		code:      new(expvar.Map).Init(),
+	}
+	c.root.Add("total", 0)
+	c.root.Set("kind", c.kind)
+	c.root.Set("status", c.status)
+	c.root.Set("retryable", c.retryable)
+	c.root.Set("code", c.code)
+	return c
+}
+
+func (c *counters) add(err error) {
+	l := errorutil.ErrorLabels(err)
+	c.root.Add("total", 1)
+	c.kind.Add(string(l.Kind), 1)
+	c.status.Add(l.StatusClass, 1)
+	c.retryable.Add(l.Retryable, 1)
+	if l.Code != "" {
+		c.code.Add(l.Code, 1)
+	}
+}
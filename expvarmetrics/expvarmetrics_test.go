@@ -0,0 +1,123 @@
+package expvarmetrics
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/objenious/errorutil"
+	"github.com/objenious/errorutil/backoffutil"
+)
+
+func decode(t *testing.T, v expvar.Var) map[string]interface{} {
+	t.Helper()
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", v, err)
+	}
+	return got
+}
+
+// published counts the metrics published by the tests, to give them unique names when tests are run several times.
+var published int32
+
+func publishName(prefix string) string {
+	return fmt.Sprintf("%s_%d", prefix, atomic.AddInt32(&published, 1))
+}
+
+func TestNew(t *testing.T) {
+	name := publishName("errorutil_test")
+	m := New(name)
+	if expvar.Get(name) != m {
+		t.Fatalf("New: the metrics must be published")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := newMetrics([]time.Duration{time.Second, time.Minute})
+	m.ServerError(errorutil.WithCode(errorutil.NotFoundError(errors.New("foo")), "user.not_found"))
+	m.ServerError(errors.New("foo"))
+	m.ClientError(errorutil.RetryableError(errors.New("foo")))
+	m.Retry(errorutil.RetryableError(errors.New("foo")), 500*time.Millisecond)
+	m.Retry(errorutil.ServiceUnavailableError(errors.New("foo")), 2*time.Second)
+	m.Retry(errorutil.WithCode(errorutil.RetryableError(errors.New("foo")), "db.unavailable"), time.Hour)
+
+	got := decode(t, m)
+	want := map[string]interface{}{
+		"server": map[string]interface{}{
+			"total":     2.0,
+			"kind":      map[string]interface{}{"not_found": 1.0, "internal": 1.0},
+			"status":    map[string]interface{}{"4xx": 1.0, "5xx": 1.0},
+			"retryable": map[string]interface{}{"unknown": 2.0},
+			"code":      map[string]interface{}{"user.not_found": 1.0},
+		},
+		"client": map[string]interface{}{
+			"total":     1.0,
+			"kind":      map[string]interface{}{"internal": 1.0},
+			"status":    map[string]interface{}{"5xx": 1.0},
+			"retryable": map[string]interface{}{"true": 1.0},
+			"code":      map[string]interface{}{},
+		},
+		"retry": map[string]interface{}{
+			"total":         3.0,
+			"kind":          map[string]interface{}{"internal": 2.0, "unavailable": 1.0},
+			"status":        map[string]interface{}{"5xx": 3.0},
+			"retryable":     map[string]interface{}{"true": 3.0},
+			"code":          map[string]interface{}{"db.unavailable": 1.0},
+			"delay_seconds": 3602.5,
+			"delay":         map[string]interface{}{"le=1s": 1.0, "le=1m0s": 2.0, "le=+Inf": 3.0},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Metrics: got %v, want %v", got, want)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	m := newMetrics(DefaultBuckets)
+	errorutil.SetMetrics(m)
+	defer errorutil.SetMetrics(nil)
+
+	errorutil.WriteError(httptest.NewRecorder(), errorutil.InvalidError(errors.New("foo")))
+	errorutil.HTTPError(&http.Response{StatusCode: http.StatusConflict})
+	attempts := 0
+	backoffutil.Retry(func() error {
+		attempts++
+		if attempts == 1 {
+			return errorutil.RetryableError(errors.New("foo"))
+		}
+		return nil
+	})
+
+	got := decode(t, m)
+	for _, counter := range []struct {
+		path []string
+		want float64
+	}{
+		{[]string{"server", "total"}, 1},
+		{[]string{"client", "total"}, 1},
+		{[]string{"retry", "total"}, 1},
+		{[]string{"retry", "retryable", "true"}, 1},
+		{[]string{"retry", "delay", "le=+Inf"}, 1},
+	} {
+		var v interface{} = got
+		for _, key := range counter.path {
+			v = v.(map[string]interface{})[key]
+		}
+		if v != counter.want {
+			t.Errorf("%v: got %v, want %v", counter.path, v, counter.want)
+		}
+	}
+}
+
+func Example() {
+	errorutil.SetMetrics(New("errors"))
+	// counters are served by the /debug/vars handler of the expvar package
+}
@@ -0,0 +1,47 @@
+package errorutil
+
+// Fielder defines errors carrying named values, such as the parameters of their public message.
+type Fielder interface {
+	Fields() map[string]string
+}
+
+// WithFields attaches named values to an error. It returns nil if the error is nil.
+func WithFields(err error, fields map[string]string) error {
+	if err == nil {
+		return nil
+	}
+	t := tag(err)
+	t.fields = mergeFields(mergeFields(nil, fields), t.fields)
+	return t
+}
+
+// Fields returns the named values of an error and its causes (i.e. implementing Fielder).
+// When several errors of the chain define the same field, the outermost value is returned.
+//
+// If the error is nil or has no fields, nil is returned.
+func Fields(err error) map[string]string {
+	type causer interface {
+		Cause() error
+	}
+
+	var fields map[string]string
+	for err != nil {
+		if t, ok := err.(*taggedError); ok {
+			fields = mergeFields(fields, t.fields)
+			err = t.err
+			continue
+		}
+		if f, ok := err.(Fielder); ok {
+			fields = mergeFields(fields, f.Fields())
+		}
+		if t := classify(err); t != nil {
+			fields = mergeFields(fields, t.fields)
+		}
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return fields
+}
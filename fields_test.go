@@ -0,0 +1,32 @@
+package errorutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		err  error
+		want map[string]string
+	}{
+		{nil, nil},
+		{WithFields(nil, map[string]string{"id": "42"}), nil},
+		{errors.New("foo"), nil},
+		{WithFields(errors.New("foo"), map[string]string{"id": "42"}), map[string]string{"id": "42"}},
+		{oerrors.Wrap(NotFoundError(WithFields(errors.New("foo"), map[string]string{"id": "42"})), "bar"), map[string]string{"id": "42"}},
+		{
+			WithFields(WithFields(errors.New("foo"), map[string]string{"id": "42", "org": "foo"}), map[string]string{"id": "43"}),
+			map[string]string{"id": "43", "org": "foo"},
+		},
+	}
+	for _, tt := range tests {
+		got := Fields(tt.err)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Fields(%q): got %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
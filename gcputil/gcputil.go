@@ -0,0 +1,128 @@
+// Package gcputil classifies the errors returned by Google Cloud APIs : *googleapi.Error (REST APIs),
+// gRPC status errors (gRPC APIs, through gax) and the sentinel errors of cloud.google.com/go/storage.
+//
+// Importing the package registers a classifier (see errorutil.RegisterClassifier), so that errorutil.HTTPStatusCode,
+// errorutil.IsRetryable, errorutil.Delay... understand these errors :
+//
+//	import _ "github.com/objenious/errorutil/gcputil"
+//
+// *googleapi.Error errors keep their status code. They are retryable for 429, 500, 502, 503 and 504 status codes.
+// They are also retryable for the rateLimitExceeded and userRateLimitExceeded reasons (429 Too Many Requests),
+// and for the backendError reason (503 Service Unavailable). The delay is read from the Retry-After header.
+//
+// gRPC errors are mapped to the equivalent HTTP status code. They are retryable for the Unavailable, ResourceExhausted,
+// Aborted and DeadlineExceeded codes, and the delay is read from the RetryInfo detail.
+//
+// The kind of these errors (see errorutil.KindOf) is derived from their status code : for instance, rate limit errors
+// are errorutil.KindRateLimited, and Unavailable or DeadlineExceeded gRPC errors are errorutil.KindUnavailable.
+package gcputil
+
+import (
+	"net/http"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/objenious/errorutil"
+)
+
+func init() {
+	errorutil.RegisterClassifier(Classify)
+}
+
+// Classify returns the tags of a Google Cloud API error. It returns nil for other errors.
+// Only err is checked, not its causes.
+func Classify(err error) []errorutil.TagOption {
+	switch err {
+	case storage.ErrObjectNotExist, storage.ErrBucketNotExist:
+		return []errorutil.TagOption{errorutil.Status(http.StatusNotFound), errorutil.NotRetryable()}
+	}
+	if gerr, ok := err.(*googleapi.Error); ok {
+		return classifyAPIError(gerr)
+	}
+	if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		if s, ok := status.FromError(err); ok && s.Code() != codes.OK {
+			return classifyStatus(s)
+		}
+	}
+	return nil
+}
+
+// reasons are the retryable reasons of googleapi.Error errors, with their status code
+// (some APIs return rate limit errors with a 403 Forbidden status code).
+var reasons = map[string]int{
+	"rateLimitExceeded":     http.StatusTooManyRequests,
+	"userRateLimitExceeded": http.StatusTooManyRequests,
+	"backendError":          http.StatusServiceUnavailable,
+}
+
+func classifyAPIError(err *googleapi.Error) []errorutil.TagOption {
+	code := err.Code
+	retryable := false
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		retryable = true
+	}
+	for _, item := range err.Errors {
+		if status, ok := reasons[item.Reason]; ok {
+			code = status
+			retryable = true
+			break
+		}
+	}
+	opts := []errorutil.TagOption{errorutil.Status(code)}
+	if !retryable {
+		return append(opts, errorutil.NotRetryable())
+	}
+	opts = append(opts, errorutil.Retryable())
+	if d := errorutil.ParseRetryAfter(err.Header.Get(errorutil.HeaderRetryAfter)); d > 0 {
+		opts = append(opts, errorutil.After(d))
+	}
+	return opts
+}
+
+// statuses maps gRPC codes to HTTP status codes, and tells whether they are retryable.
+var statuses = map[codes.Code]struct {
+	status    int
+	retryable bool
+}{
+	codes.Canceled:           {errorutil.StatusCanceled, false},
+	codes.Unknown:            {http.StatusInternalServerError, false},
+	codes.InvalidArgument:    {http.StatusBadRequest, false},
+	codes.DeadlineExceeded:   {http.StatusGatewayTimeout, true},
+	codes.NotFound:           {http.StatusNotFound, false},
+	codes.AlreadyExists:      {http.StatusConflict, false},
+	codes.PermissionDenied:   {http.StatusForbidden, false},
+	codes.ResourceExhausted:  {http.StatusTooManyRequests, true},
+	codes.FailedPrecondition: {http.StatusBadRequest, false},
+	codes.Aborted:            {http.StatusConflict, true},
+	codes.OutOfRange:         {http.StatusBadRequest, false},
+	codes.Unimplemented:      {http.StatusNotImplemented, false},
+	codes.Internal:           {http.StatusInternalServerError, false},
+	codes.Unavailable:        {http.StatusServiceUnavailable, true},
+	codes.DataLoss:           {http.StatusInternalServerError, false},
+	codes.Unauthenticated:    {http.StatusUnauthorized, false},
+}
+
+func classifyStatus(s *status.Status) []errorutil.TagOption {
+	c, ok := statuses[s.Code()]
+	if !ok {
+		return nil
+	}
+	opts := []errorutil.TagOption{errorutil.Status(c.status)}
+	if !c.retryable {
+		return append(opts, errorutil.NotRetryable())
+	}
+	opts = append(opts, errorutil.Retryable())
+	for _, detail := range s.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay != nil {
+			if d := info.RetryDelay.AsDuration(); d > 0 {
+				opts = append(opts, errorutil.After(d))
+			}
+		}
+	}
+	return opts
+}
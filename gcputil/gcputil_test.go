@@ -0,0 +1,125 @@
+package gcputil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	oerrors "github.com/objenious/errors"
+	"github.com/objenious/errorutil"
+)
+
+func retryInfo(t *testing.T, c codes.Code, delay time.Duration) error {
+	s, err := status.New(c, "foo").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Err()
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err          error
+		status       int
+		retryable    bool
+		notRetryable bool
+		delay        time.Duration
+	}{
+		{storage.ErrObjectNotExist, http.StatusNotFound, false, true, 0},
+		{oerrors.Wrap(storage.ErrBucketNotExist, "read"), http.StatusNotFound, false, true, 0},
+		{&googleapi.Error{Code: http.StatusNotFound}, http.StatusNotFound, false, true, 0},
+		{&googleapi.Error{Code: http.StatusBadRequest}, http.StatusBadRequest, false, true, 0},
+		{&googleapi.Error{Code: http.StatusServiceUnavailable}, http.StatusServiceUnavailable, true, false, 0},
+		{&googleapi.Error{Code: http.StatusInternalServerError}, http.StatusInternalServerError, true, false, 0},
+		{&googleapi.Error{Code: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}}, http.StatusTooManyRequests, true, false, 30 * time.Second},
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, http.StatusTooManyRequests, true, false, 0},
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}}}, http.StatusTooManyRequests, true, false, 0},
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}}, http.StatusForbidden, false, true, 0},
+		{oerrors.Wrap(&googleapi.Error{Code: http.StatusInternalServerError, Errors: []googleapi.ErrorItem{{Reason: "backendError"}}}, "bar"), http.StatusServiceUnavailable, true, false, 0},
+		{status.Error(codes.NotFound, "foo"), http.StatusNotFound, false, true, 0},
+		{status.Error(codes.AlreadyExists, "foo"), http.StatusConflict, false, true, 0},
+		{status.Error(codes.InvalidArgument, "foo"), http.StatusBadRequest, false, true, 0},
+		{status.Error(codes.PermissionDenied, "foo"), http.StatusForbidden, false, true, 0},
+		{status.Error(codes.Unauthenticated, "foo"), http.StatusUnauthorized, false, true, 0},
+		{status.Error(codes.Canceled, "foo"), errorutil.StatusCanceled, false, true, 0},
+		{status.Error(codes.Unavailable, "foo"), http.StatusServiceUnavailable, true, false, 0},
+		{status.Error(codes.DeadlineExceeded, "foo"), http.StatusGatewayTimeout, true, false, 0},
+		{oerrors.Wrap(status.Error(codes.Aborted, "foo"), "bar"), http.StatusConflict, true, false, 0},
+		{fmt.Errorf("bar: %w", status.Error(codes.Aborted, "foo")), http.StatusConflict, false, false, 0},
+		{retryInfo(t, codes.ResourceExhausted, 10*time.Second), http.StatusTooManyRequests, true, false, 10 * time.Second},
+		{retryInfo(t, codes.NotFound, 10*time.Second), http.StatusNotFound, false, true, 0},
+		{errors.New("foo"), http.StatusInternalServerError, false, false, 0},
+		{errorutil.RetryableError(status.Error(codes.NotFound, "foo")), http.StatusInternalServerError, true, false, 0},
+	}
+	for _, tt := range tests {
+		if got := errorutil.HTTPStatusCode(tt.err); got != tt.status {
+			t.Errorf("HTTPStatusCode(%v): got %d, want %d", tt.err, got, tt.status)
+		}
+		if got := errorutil.IsRetryable(tt.err); got != tt.retryable {
+			t.Errorf("IsRetryable(%v): got %v, want %v", tt.err, got, tt.retryable)
+		}
+		if got := errorutil.IsNotRetryable(tt.err); got != tt.notRetryable {
+			t.Errorf("IsNotRetryable(%v): got %v, want %v", tt.err, got, tt.notRetryable)
+		}
+		if got := errorutil.Delay(tt.err); got != tt.delay {
+			t.Errorf("Delay(%v): got %v, want %v", tt.err, got, tt.delay)
+		}
+		a := errorutil.Inspector{StringFallbacks: true}.Inspect(tt.err)
+		if a.Status != tt.status || a.Retryable != tt.retryable || a.NotRetryable != tt.notRetryable || a.Delay != tt.delay {
+			t.Errorf("Inspect(%v): got %+v", tt.err, a)
+		}
+	}
+}
+
+func TestKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want errorutil.Kind
+	}{
+		{storage.ErrObjectNotExist, errorutil.KindNotFound},
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, errorutil.KindRateLimited},
+		{&googleapi.Error{Code: http.StatusInternalServerError, Errors: []googleapi.ErrorItem{{Reason: "backendError"}}}, errorutil.KindUnavailable},
+		{status.Error(codes.PermissionDenied, "foo"), errorutil.KindPermissionDenied},
+		{status.Error(codes.FailedPrecondition, "foo"), errorutil.KindInvalid},
+		{status.Error(codes.Canceled, "foo"), errorutil.KindCanceled},
+		{status.Error(codes.DeadlineExceeded, "foo"), errorutil.KindUnavailable},
+		{status.Error(codes.Internal, "foo"), errorutil.KindInternal},
+	}
+	for _, tt := range tests {
+		if got := errorutil.KindOf(tt.err); got != tt.want {
+			t.Errorf("KindOf(%v): got %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyIdentity(t *testing.T) {
+	tests := []error{
+		errors.New(storage.ErrObjectNotExist.Error()),
+		errors.New("foo"),
+		status.Error(codes.OK, "foo"),
+		nil,
+	}
+	for _, err := range tests {
+		if got := Classify(err); got != nil {
+			t.Errorf("Classify(%v): got %d options, want none", err, len(got))
+		}
+	}
+}
+
+func ExampleClassify() {
+	err := oerrors.Wrap(&googleapi.Error{
+		Code:   http.StatusForbidden,
+		Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}},
+	}, "get object")
+	fmt.Println(errorutil.HTTPStatusCode(err), errorutil.IsRetryable(err))
+	// Output: 429 true
+}
@@ -1,9 +1,31 @@
 module github.com/objenious/errorutil
 
-go 1.13
+go 1.18
 
 require (
 	cloud.google.com/go/storage v1.15.0
 	github.com/cenkalti/backoff v2.2.1+incompatible
 	github.com/objenious/errors v0.9.1
+	google.golang.org/api v0.45.0
+	google.golang.org/genproto v0.0.0-20210420162539-3c870d7478d2
+	google.golang.org/grpc v1.37.0
+	google.golang.org/protobuf v1.26.0
+)
+
+require (
+	cloud.google.com/go v0.81.0 // indirect
+	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/googleapis/gax-go/v2 v2.0.5 // indirect
+	github.com/jstemmer/go-junit-report v0.9.1 // indirect
+	go.opencensus.io v0.23.0 // indirect
+	golang.org/x/lint v0.0.0-20201208152925-83fdc39ff7b5 // indirect
+	golang.org/x/mod v0.4.1 // indirect
+	golang.org/x/net v0.0.0-20210316092652-d523dce5a7f4 // indirect
+	golang.org/x/oauth2 v0.0.0-20210413134643-5e61552d6c78 // indirect
+	golang.org/x/sys v0.0.0-20210412220455-f1c623a9e750 // indirect
+	golang.org/x/text v0.3.5 // indirect
+	golang.org/x/tools v0.1.0 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+	google.golang.org/appengine v1.6.7 // indirect
 )
@@ -34,28 +34,24 @@ func HTTPStatusCode(err error) int {
 	}
 
 	for err != nil {
+		if t, ok := err.(*taggedError); ok {
+			if t.mask&hasStatus != 0 {
+				return t.status
+			}
+			err = t.err
+			continue
+		}
 		if status, ok := err.(HTTPStatusCodeEr); ok {
 			return status.HTTPStatusCode()
 		}
 		if status, ok := err.(StatusCodeEr); ok {
 			return status.StatusCode()
 		}
-		// Check errors from stdlib. Test string to avoid importing packages
-		switch err.Error() {
-		// package os
-		case "permission denied":
-			return http.StatusForbidden
-		case "file does not exist":
-			return http.StatusNotFound
-		case "storage: bucket doesn't exist":
-			return http.StatusNotFound
-		case "storage: object doesn't exist":
-			return http.StatusNotFound
-		// package database/sql
-		case "sql: no rows in result set":
-			return http.StatusNotFound
-		case "i/o timeout", "TLS handshake timeout":
-			return http.StatusRequestTimeout
+		if t := classify(err); t != nil && t.mask&hasStatus != 0 {
+			return t.status
+		}
+		if status, ok := fallbackStatus(err); ok {
+			return status
 		}
 		cause, ok := err.(causer)
 		if ok {
@@ -67,13 +63,45 @@ func HTTPStatusCode(err error) int {
 	return http.StatusInternalServerError
 }
 
+// fallbackStatus recognizes some stdlib errors by their text.
+func fallbackStatus(err error) (int, bool) {
+	// Check errors from stdlib. Test string to avoid importing packages
+	switch err.Error() {
+	// package os
+	case "permission denied":
+		return http.StatusForbidden, true
+	case "file does not exist":
+		return http.StatusNotFound, true
+	case "storage: bucket doesn't exist":
+		return http.StatusNotFound, true
+	case "storage: object doesn't exist":
+		return http.StatusNotFound, true
+	// package database/sql
+	case "sql: no rows in result set":
+		return http.StatusNotFound, true
+	case "i/o timeout", "TLS handshake timeout":
+		return http.StatusRequestTimeout, true
+	}
+	return 0, false
+}
+
 // HTTPError builds an error based on a http.Response. If status code is < 300 or 304, nil is returned.
 // Otherwise, errors implementing the various interfaces (Retryabler, HTTPStatusCodeEr) are returned
+//
+// The classification set by WriteError (HeaderRetryable, HeaderCode and HeaderRetryAfter headers) is
+// taken into account : the returned error also implements Delayer and Coder, and the retryable header overrides
+// the default retryability of the status code. The WWW-Authenticate challenges are available with Challenges,
+// and the ETag of StatusConflict and StatusPreconditionFailed responses with Versions.
+// StatusTooManyRequests responses with rate limit headers are returned as a *QuotaError.
 func HTTPError(resp *http.Response) error {
 	if resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
 		return nil
 	}
-	return httpError(resp.StatusCode)
+	err := newResponseError(resp)
+	if m := GetMetrics(); m != nil {
+		m.ClientError(err)
+	}
+	return err
 }
 
 type httpError int
@@ -0,0 +1,230 @@
+// Package i18n translates the public messages of errors, using message catalogs keyed by error code or HTTP status code.
+//
+// A catalog is a set of JSON files, one per language, named after the language tag (e.g. "en.json", "fr.json", "fr-CA.json") :
+//
+//	{
+//	  "user.not_found": "L'utilisateur {id} n'existe pas",
+//	  "404": "Ressource introuvable"
+//	}
+//
+// Messages are templates, whose {field} placeholders are replaced by the fields of the error (see errorutil.Fields).
+// Files are usually embedded in the binary :
+//
+//	//go:embed messages/*.json
+//	var messages embed.FS
+//
+//	catalog, err := i18n.Load(messages, "messages/*.json", "en")
+//
+// A Catalog implements errorutil.Localizer, to be used with errorutil.WriteLocalizedError.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/objenious/errorutil"
+)
+
+// Catalog holds the messages of several languages.
+type Catalog struct {
+	defaultLang string
+	// messages by language (lower case), then by key
+	messages map[string]map[string]string
+	// tags are the language tags, as named by the files
+	tags map[string]string
+}
+
+// Load reads the message files matching a glob pattern. Each file is named after its language tag.
+// defaultLang is the language used when none of the languages accepted by the client is available.
+func Load(fsys fs.FS, pattern, defaultLang string) (*Catalog, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{
+		defaultLang: strings.ToLower(defaultLang),
+		messages:    map[string]map[string]string{},
+		tags:        map[string]string{},
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %v", file, err)
+		}
+		tag := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		c.Add(tag, messages)
+	}
+	if _, ok := c.messages[c.defaultLang]; !ok {
+		return nil, fmt.Errorf("i18n: no messages for the default language %q", defaultLang)
+	}
+	return c, nil
+}
+
+// Add adds messages for a language, replacing existing messages with the same keys.
+func (c *Catalog) Add(tag string, messages map[string]string) {
+	lang := strings.ToLower(tag)
+	if c.messages[lang] == nil {
+		c.messages[lang] = map[string]string{}
+	}
+	c.tags[lang] = tag
+	for key, msg := range messages {
+		c.messages[lang][key] = msg
+	}
+}
+
+// Validate checks that every message of the default language is translated in every language,
+// and that translations do not use keys or fields unknown to the default language.
+// Regional variants (e.g. "fr-CA") may omit messages of their base language ("fr"), which they fall back to.
+func (c *Catalog) Validate() error {
+	var problems []string
+	ref := c.messages[c.defaultLang]
+	for lang, messages := range c.messages {
+		if lang == c.defaultLang {
+			continue
+		}
+		// regional variants fall back to their base language
+		if _, ok := c.messages[base(lang)]; !ok || base(lang) == lang {
+			for key := range ref {
+				if _, ok := messages[key]; !ok {
+					problems = append(problems, fmt.Sprintf("%s: missing translation of %q", c.tags[lang], key))
+				}
+			}
+		}
+		for key, translation := range messages {
+			msg, ok := ref[key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: %q is missing in the default language", c.tags[lang], key))
+				continue
+			}
+			for field := range placeholders(translation) {
+				if !placeholders(msg)[field] {
+					problems = append(problems, fmt.Sprintf("%s: unknown field %q in %q", c.tags[lang], field, key))
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("i18n: invalid catalog:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// Localize returns the message of an error in the best language matching an Accept-Language header.
+//
+// The message is looked up by error code (see errorutil.Code), then by HTTP status code.
+// If the error has an explicit public message (see errorutil.WithPublicMessage) but no translated code,
+// the public message is preferred over the translation of the status code.
+//
+// For each accepted language (e.g. "fr-CA"), the language is tried, then its base language ("fr").
+// The default language is tried last.
+func (c *Catalog) Localize(err error, acceptLanguage string) (msg, lang string, ok bool) {
+	if err == nil {
+		return "", "", false
+	}
+	langs := c.match(acceptLanguage)
+	fields := errorutil.Fields(err)
+	if code := errorutil.Code(err); code != "" {
+		if msg, lang, ok := c.lookup(langs, code); ok {
+			return format(msg, fields), lang, true
+		}
+	}
+	if _, ok := errorutil.PublicMessage(err); ok {
+		return "", "", false
+	}
+	if msg, lang, ok := c.lookup(langs, strconv.Itoa(errorutil.HTTPStatusCode(err))); ok {
+		return format(msg, fields), lang, true
+	}
+	return "", "", false
+}
+
+func (c *Catalog) lookup(langs []string, key string) (msg, tag string, ok bool) {
+	for _, lang := range langs {
+		if msg, ok := c.messages[lang][key]; ok {
+			return msg, c.tags[lang], true
+		}
+	}
+	return "", "", false
+}
+
+// match returns the languages to try, in order, for an Accept-Language header.
+func (c *Catalog) match(acceptLanguage string) []string {
+	type accepted struct {
+		lang string
+		q    float64
+	}
+	var accepts []accepted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		lang := strings.ToLower(strings.TrimSpace(fields[0]))
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			accepts = append(accepts, accepted{lang: lang, q: q})
+		}
+	}
+	sort.SliceStable(accepts, func(i, j int) bool { return accepts[i].q > accepts[j].q })
+
+	var langs []string
+	seen := map[string]bool{}
+	add := func(lang string) {
+		if _, ok := c.messages[lang]; ok && !seen[lang] {
+			seen[lang] = true
+			langs = append(langs, lang)
+		}
+	}
+	for _, a := range accepts {
+		add(a.lang)
+		add(base(a.lang))
+	}
+	add(c.defaultLang)
+	return langs
+}
+
+// base returns the base language of a language tag, e.g. "fr" for "fr-ca".
+func base(lang string) string {
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		return lang[:i]
+	}
+	return lang
+}
+
+var placeholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+func placeholders(msg string) map[string]bool {
+	fields := map[string]bool{}
+	for _, m := range placeholder.FindAllStringSubmatch(msg, -1) {
+		fields[m[1]] = true
+	}
+	return fields
+}
+
+// format replaces the {field} placeholders of a message.
+func format(msg string, fields map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(msg, func(m string) string {
+		if v, ok := fields[m[1:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
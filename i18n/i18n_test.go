@@ -0,0 +1,107 @@
+package i18n
+
+import (
+	"embed"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/objenious/errorutil"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+func load(t *testing.T) *Catalog {
+	t.Helper()
+	c, err := Load(testdata, "testdata/valid/*.json", "en")
+	if err != nil {
+		t.Fatalf("Load: unexpected error %v", err)
+	}
+	return c
+}
+
+func TestLocalize(t *testing.T) {
+	c := load(t)
+	notFound := errorutil.WithCode(errorutil.WithFields(errorutil.NotFoundError(errors.New("foo")), map[string]string{"id": "42"}), "user.not_found")
+	tests := []struct {
+		err            error
+		acceptLanguage string
+		msg, lang      string
+		ok             bool
+	}{
+		{nil, "fr", "", "", false},
+		{notFound, "", "User 42 not found", "en", true},
+		{notFound, "fr", "L'utilisateur 42 n'existe pas", "fr", true},
+		{notFound, "fr-CA", "L'utilisateur 42 n'existe pas", "fr", true},
+		{notFound, "de, fr;q=0.5, en;q=0.8", "User 42 not found", "en", true},
+		{notFound, "de, fr;q=0.9, en;q=0.8", "L'utilisateur 42 n'existe pas", "fr", true},
+		{notFound, "de, fr;q=0, *", "User 42 not found", "en", true},
+		{errorutil.NotFoundError(errors.New("foo")), "fr-CA", "Introuvable (CA)", "fr-CA", true},
+		{errorutil.NotFoundError(errors.New("foo")), "fr-BE", "Introuvable", "fr", true},
+		{errors.New("foo"), "fr", "Erreur interne", "fr", true},
+		{errorutil.WithCode(errors.New("foo"), "unknown"), "fr", "Erreur interne", "fr", true},
+		{errorutil.WithPublicMessage(errors.New("foo"), "Oops"), "fr", "", "", false},
+		{errorutil.ConflictError(errors.New("foo")), "fr", "", "", false},
+	}
+	for _, tt := range tests {
+		msg, lang, ok := c.Localize(tt.err, tt.acceptLanguage)
+		if msg != tt.msg || lang != tt.lang || ok != tt.ok {
+			t.Errorf("Localize(%q, %q): got %q, %q, %v, want %q, %q, %v", tt.err, tt.acceptLanguage, msg, lang, ok, tt.msg, tt.lang, tt.ok)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := load(t).Validate(); err != nil {
+		t.Errorf("Validate: unexpected error %v", err)
+	}
+	c, err := Load(testdata, "testdata/invalid/*.json", "en")
+	if err != nil {
+		t.Fatalf("Load: unexpected error %v", err)
+	}
+	err = c.Validate()
+	if err == nil {
+		t.Fatalf("Validate: expected an error")
+	}
+	for _, problem := range []string{
+		`fr: missing translation of "404"`,
+		`fr: unknown field "name" in "user.not_found"`,
+		`fr: "409" is missing in the default language`,
+		`fr-CA: unknown field "login" in "user.not_found"`,
+		`fr-CA: "410" is missing in the default language`,
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Validate: got %v, want %q", err, problem)
+		}
+	}
+	// regional variants fall back to their base language
+	if strings.Contains(err.Error(), "fr-CA: missing translation") {
+		t.Errorf("Validate: got %v, regional variants may omit translations", err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(testdata, "testdata/valid/*.json", "de"); err == nil {
+		t.Errorf("Load: the default language must exist")
+	}
+}
+
+func TestWriteLocalizedError(t *testing.T) {
+	c := load(t)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "fr-FR,fr;q=0.9")
+	w := httptest.NewRecorder()
+	errorutil.WriteLocalizedError(w, r, errorutil.NotFoundError(errors.New("sql: no rows in result set")), c)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Language") != "fr" || !strings.Contains(w.Body.String(), `"detail":"Introuvable"`) {
+		t.Errorf("WriteLocalizedError: got %d %v %s", w.Code, w.Header(), w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	errorutil.WriteLocalizedError(w, r, errorutil.WithPublicMessage(errors.New("foo"), "Oops"), c)
+	if w.Header().Get("Content-Language") != "" || !strings.Contains(w.Body.String(), `"detail":"Oops"`) {
+		t.Errorf("WriteLocalizedError: got %d %v %s", w.Code, w.Header(), w.Body)
+	}
+}
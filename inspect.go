@@ -0,0 +1,225 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Attributes are the tags of an error, as returned by the individual inspection functions.
+type Attributes struct {
+	// Status is the HTTP status code (see HTTPStatusCode).
+	Status int
+	// Retryable is true if the error is retryable (see IsRetryable).
+	Retryable bool
+	// NotRetryable is true if the error is explicitly not retryable (see IsNotRetryable).
+	NotRetryable bool
+	// Delay is the delay of the error (see Delay).
+	Delay time.Duration
+	// Code is the code of the error (see Code).
+	Code string
+	// PublicMessage is the public message of the error, and HasPublicMessage whether it was explicitly set (see PublicMessage).
+	PublicMessage    string
+	HasPublicMessage bool
+	// Fields are the fields of the error (see Fields).
+	Fields map[string]string
+}
+
+// Inspector resolves all the attributes of an error in a single walk of its cause chain.
+type Inspector struct {
+	// StringFallbacks enables the recognition of some stdlib errors by their text, as done by HTTPStatusCode.
+	// It requires calling Error() at each level of the chain.
+	StringFallbacks bool
+}
+
+// Inspect returns the attributes of an error, without string fallbacks : Error() is never called,
+// so errors that are only recognized by their text (e.g. os.ErrNotExist) have a StatusInternalServerError status.
+//
+// Use it on hot paths needing several attributes of the same error, instead of calling
+// IsRetryable, Delay, HTTPStatusCode... that each walk the chain.
+func Inspect(err error) Attributes {
+	return Inspector{}.Inspect(err)
+}
+
+// Inspect returns the attributes of an error. They are the same as the ones returned by the individual
+// inspection functions (if StringFallbacks is enabled).
+func (in Inspector) Inspect(err error) Attributes {
+	var a Attributes
+	if err == nil {
+		a.Status = http.StatusOK
+		return a
+	}
+	type causer interface {
+		Cause() error
+	}
+
+	var retryable, delay, code, public, status bool
+	// only the status code is looked up through Unwrap, the other attributes stop at the first non-causer
+	causes := true
+	apply := func(t *taggedError) {
+		if !status && t.mask&hasStatus != 0 {
+			a.Status, status = t.status, true
+		}
+		if causes {
+			if !retryable && t.mask&hasRetryable != 0 {
+				a.Retryable, a.NotRetryable, retryable = t.retryable, !t.retryable, true
+			}
+			if !delay && t.mask&hasDelay != 0 {
+				a.Delay, delay = t.delay, true
+			}
+			if !code && t.code != "" {
+				a.Code, code = t.code, true
+			}
+			if !public && t.mask&hasPublicMessage != 0 {
+				a.PublicMessage, a.HasPublicMessage, public = t.publicMessage, true, true
+			}
+			a.Fields = mergeFields(a.Fields, t.fields)
+		}
+	}
+	for e := err; e != nil; {
+		if t, ok := e.(*taggedError); ok {
+			apply(t)
+			e = t.err
+			continue
+		}
+		var classified *taggedError
+		if !status {
+			if s, ok := e.(HTTPStatusCodeEr); ok {
+				a.Status, status = s.HTTPStatusCode(), true
+			} else if s, ok := e.(StatusCodeEr); ok {
+				a.Status, status = s.StatusCode(), true
+			} else if classified = classify(e); classified != nil && classified.mask&hasStatus != 0 {
+				a.Status, status = classified.status, true
+			} else if in.StringFallbacks {
+				a.Status, status = fallbackStatus(e)
+			}
+		}
+		if causes {
+			if !retryable {
+				if r, ok := e.(Retryabler); ok {
+					a.Retryable = r.Retryable()
+					a.NotRetryable, retryable = !a.Retryable, true
+				}
+			}
+			if !delay {
+				if d, ok := e.(Delayer); ok {
+					a.Delay, delay = d.Delay(), true
+				}
+			}
+			if !code {
+				if c, ok := e.(Coder); ok {
+					a.Code = c.Code()
+					code = a.Code != ""
+				}
+			}
+			if !public {
+				if p, ok := e.(PublicMessager); ok {
+					a.PublicMessage, a.HasPublicMessage, public = p.PublicMessage(), true, true
+				}
+			}
+			if f, ok := e.(Fielder); ok {
+				a.Fields = mergeFields(a.Fields, f.Fields())
+			}
+			if classified == nil {
+				classified = classify(e)
+			}
+			if classified != nil {
+				apply(classified)
+			}
+		}
+		if cause, ok := e.(causer); ok {
+			e = cause.Cause()
+			continue
+		}
+		if status {
+			break
+		}
+		causes = false
+		e = errors.Unwrap(e)
+	}
+	if !status {
+		a.Status = http.StatusInternalServerError
+	}
+	if !public {
+		a.PublicMessage = defaultPublicMessage(a.Status)
+	}
+	return a
+}
+
+// mergeFields adds the fields of an inner error, keeping the existing (outer) values.
+func mergeFields(fields, inner map[string]string) map[string]string {
+	for k, v := range inner {
+		if fields == nil {
+			fields = make(map[string]string, len(inner))
+		}
+		if _, ok := fields[k]; !ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+type attrMask uint8
+
+const (
+	hasRetryable attrMask = 1 << iota
+	hasDelay
+	hasStatus
+	hasPublicMessage
+	hasVersions
+)
+
+// taggedError stores several attributes in a single wrapper.
+// Tagging a taggedError again copies it instead of stacking another wrapper : the copy matches the original
+// with errors.Is. All inspection functions understand it.
+type taggedError struct {
+	err           error
+	mask          attrMask
+	retryable     bool
+	status        int
+	delay         time.Duration
+	code          string
+	publicMessage string
+	fields        map[string]string
+	values        []keyValue
+	challenges    []string
+	// versions of a resource, see Versions
+	currentVersion  string
+	expectedVersion string
+	// from is the taggedError this one was copied from, if any
+	from *taggedError
+}
+
+// tag returns a taggedError wrapping err, to be completed by the caller.
+// If err is already a taggedError, a copy is returned. err must not be nil.
+func tag(err error) *taggedError {
+	if inner, ok := err.(*taggedError); ok {
+		t := *inner
+		t.from = inner
+		return &t
+	}
+	return &taggedError{err: err}
+}
+
+func (err *taggedError) Error() string {
+	return err.err.Error()
+}
+
+func (err *taggedError) Cause() error {
+	return err.err
+}
+
+func (err *taggedError) Unwrap() error {
+	return err.err
+}
+
+// Is reports whether target is one of the taggedErrors err was copied from,
+// as they are not part of the chain of err.
+func (err *taggedError) Is(target error) bool {
+	for from := err.from; from != nil; from = from.from {
+		if target == error(from) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package errorutil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	oerrors "github.com/objenious/errors"
+)
+
+var inspectTests = []error{
+	nil,
+	errors.New("foo"),
+	os.ErrNotExist,
+	fmt.Errorf("bar: %w", os.ErrNotExist),
+	fmt.Errorf("bar: %w", RetryableError(errors.New("foo"))),
+	RetryableError(errors.New("foo")),
+	NotRetryableError(NotFoundError(errors.New("foo"))),
+	NotFoundError(RetryableError(errors.New("foo"))),
+	WithDelay(RetryableError(errors.New("foo")), time.Minute),
+	oerrors.Wrap(WithCode(ConflictError(errors.New("foo")), "user.conflict"), "bar"),
+	WithCode(WithCode(errors.New("foo"), "inner"), ""),
+	WithPublicMessage(InvalidError(errors.New("foo")), "invalid user"),
+	WithFields(oerrors.Wrap(WithFields(errors.New("foo"), map[string]string{"a": "1", "b": "2"}), "bar"), map[string]string{"a": "3"}),
+	httpError(429),
+	newResponseError(&http.Response{StatusCode: 503, Header: http.Header{HeaderRetryAfter: {"60"}, HeaderCode: {"db.unavailable"}}}),
+}
+
+func TestInspect(t *testing.T) {
+	for _, err := range inspectTests {
+		msg, ok := PublicMessage(err)
+		want := Attributes{
+			Status:           HTTPStatusCode(err),
+			Retryable:        IsRetryable(err),
+			NotRetryable:     IsNotRetryable(err),
+			Delay:            Delay(err),
+			Code:             Code(err),
+			PublicMessage:    msg,
+			HasPublicMessage: ok,
+			Fields:           Fields(err),
+		}
+		if err == nil {
+			want.PublicMessage = ""
+		}
+		if got := (Inspector{StringFallbacks: true}).Inspect(err); !reflect.DeepEqual(got, want) {
+			t.Errorf("Inspect(%q): got %+v, want %+v", err, got, want)
+		}
+	}
+}
+
+func TestInspectWithoutFallbacks(t *testing.T) {
+	if got := Inspect(os.ErrNotExist).Status; got != http.StatusInternalServerError {
+		t.Errorf("Inspect(os.ErrNotExist): got status %d, want %d", got, http.StatusInternalServerError)
+	}
+	if got := Inspect(NotFoundError(os.ErrNotExist)).Status; got != http.StatusNotFound {
+		t.Errorf("Inspect(NotFoundError(os.ErrNotExist)): got status %d, want %d", got, http.StatusNotFound)
+	}
+}
+
+func TestTaggedErrorMerge(t *testing.T) {
+	base := errors.New("foo")
+	err := RetryableError(WithDelay(WithCode(WithPublicMessage(base, "oops"), "db.unavailable"), time.Minute))
+	if cause := err.(interface{ Cause() error }).Cause(); cause != base {
+		t.Errorf("tags must be stored in a single wrapper, got cause %#v", cause)
+	}
+	if !IsRetryable(err) || Delay(err) != time.Minute || Code(err) != "db.unavailable" {
+		t.Errorf("merged tags: got retryable %v, delay %v, code %q", IsRetryable(err), Delay(err), Code(err))
+	}
+	if msg, _ := PublicMessage(err); msg != "oops" {
+		t.Errorf("merged tags: got public message %q", msg)
+	}
+
+	inner := WithCode(base, "inner")
+	WithCode(inner, "outer")
+	if Code(inner) != "inner" {
+		t.Errorf("tagging must not modify the wrapped error, got code %q", Code(inner))
+	}
+}
+
+func TestTaggedErrorIs(t *testing.T) {
+	base := errors.New("foo")
+	errBusy := NewRetryableError("busy")
+	errTagged := WithCode(errBusy, "busy")
+	other := NewRetryableError("busy")
+	tests := []struct {
+		err    error
+		target error
+		want   bool
+	}{
+		{WithDelay(errBusy, time.Minute), errBusy, true},
+		{WithDelay(errTagged, time.Minute), errBusy, true},
+		{WithDelay(errTagged, time.Minute), errTagged, true},
+		{oerrors.Wrap(WithDelay(errBusy, time.Minute), "bar"), errBusy, true},
+		{fmt.Errorf("bar: %w", Tag(errBusy, Coded("busy"))), errBusy, true},
+		{WithDelay(errBusy, time.Minute), other, false},
+		{WithDelay(WithCode(base, "foo"), time.Minute), base, true},
+		{WithDelay(base, time.Minute), errBusy, false},
+		{errBusy, WithDelay(errBusy, time.Minute), false},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(tt.err, tt.target); got != tt.want {
+			t.Errorf("errors.Is(%v, %v): got %v, want %v", tt.err, tt.target, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkInspect(b *testing.B) {
+	err := oerrors.Wrap(oerrors.Wrap(WithDelay(RetryableError(oerrors.Wrap(NotFoundError(errors.New("foo")), "bar")), time.Second), "baz"), "qux")
+	b.Run("Individual", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = IsRetryable(err)
+			_ = IsNotRetryable(err)
+			_ = Delay(err)
+			_ = HTTPStatusCode(err)
+			_ = Code(err)
+		}
+	})
+	b.Run("Inspect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = Inspect(err)
+		}
+	})
+	b.Run("InspectWithFallbacks", func(b *testing.B) {
+		b.ReportAllocs()
+		in := Inspector{StringFallbacks: true}
+		for i := 0; i < b.N; i++ {
+			_ = in.Inspect(err)
+		}
+	})
+}
+
+var benchErr error
+
+func BenchmarkTag(b *testing.B) {
+	base := errors.New("foo")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchErr = WithCode(WithDelay(RetryableError(base), time.Second), "db.unavailable")
+	}
+}
+
+func ExampleInspect() {
+	err := WithDelay(RetryableError(errors.New("database unavailable")), time.Minute)
+	a := Inspect(err)
+	fmt.Println(a.Status, a.Retryable, a.Delay)
+	// Output: 500 true 1m0s
+}
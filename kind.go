@@ -0,0 +1,83 @@
+package errorutil
+
+import "net/http"
+
+// Kind is a coarse classification of errors, derived from their HTTP status code (see KindOf).
+// It is meant for dashboards, logs and assertions, where the exact status code is too detailed.
+type Kind string
+
+// Kinds of errors.
+const (
+	KindInvalid          Kind = "invalid"           // 400, 413, 422 and other 4xx status codes
+	KindUnauthenticated  Kind = "unauthenticated"   // 401
+	KindPermissionDenied Kind = "permission_denied" // 403
+	KindNotFound         Kind = "not_found"         // 404, 410
+	KindConflict         Kind = "conflict"          // 409, 412
+	KindRateLimited      Kind = "rate_limited"      // 429
+	KindCanceled         Kind = "canceled"          // 499
+	KindInternal         Kind = "internal"          // 500 and other 5xx status codes
+	KindNotImplemented   Kind = "not_implemented"   // 501
+	KindUnavailable      Kind = "unavailable"       // 502, 503, 504
+)
+
+// KindOf returns the kind of an error, derived from HTTPStatusCode.
+//
+// It returns an empty Kind if the error is nil.
+func KindOf(err error) Kind {
+	if err == nil {
+		return ""
+	}
+	return StatusKind(HTTPStatusCode(err))
+}
+
+// StatusKind returns the kind of errors with a HTTP status code.
+// Status codes below 400 are classified as KindInternal, as they are not error status codes.
+func StatusKind(status int) Kind {
+	switch status {
+	case http.StatusUnauthorized:
+		return KindUnauthenticated
+	case http.StatusForbidden:
+		return KindPermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return KindNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return KindConflict
+	case http.StatusTooManyRequests:
+		return KindRateLimited
+	case StatusCanceled:
+		return KindCanceled
+	case http.StatusNotImplemented:
+		return KindNotImplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return KindUnavailable
+	}
+	if status >= 400 && status < 500 {
+		return KindInvalid
+	}
+	return KindInternal
+}
+
+// KindStatus returns the main HTTP status code of a kind, or StatusInternalServerError for an unknown kind.
+func KindStatus(kind Kind) int {
+	switch kind {
+	case KindInvalid:
+		return http.StatusBadRequest
+	case KindUnauthenticated:
+		return http.StatusUnauthorized
+	case KindPermissionDenied:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindRateLimited:
+		return http.StatusTooManyRequests
+	case KindCanceled:
+		return StatusCanceled
+	case KindNotImplemented:
+		return http.StatusNotImplemented
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,58 @@
+package errorutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Kind
+	}{
+		{nil, ""},
+		{errors.New("foo"), KindInternal},
+		{os.ErrNotExist, KindNotFound},
+		{NotFoundError(errors.New("foo")), KindNotFound},
+		{oerrors.Wrap(InvalidError(errors.New("foo")), "bar"), KindInvalid},
+		{ConflictError(errors.New("foo")), KindConflict},
+		{httpError(429), KindRateLimited},
+		{httpError(405), KindInvalid},
+		{httpError(503), KindUnavailable},
+		{httpError(507), KindInternal},
+		{RetryableError(errors.New("foo")), KindInternal},
+	}
+	for _, tt := range tests {
+		if got := KindOf(tt.err); got != tt.want {
+			t.Errorf("KindOf(%v): got %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestKindStatus(t *testing.T) {
+	kinds := []Kind{
+		KindInvalid, KindUnauthenticated, KindPermissionDenied, KindNotFound, KindConflict,
+		KindRateLimited, KindCanceled, KindInternal, KindNotImplemented, KindUnavailable,
+	}
+	for _, kind := range kinds {
+		if got := StatusKind(KindStatus(kind)); got != kind {
+			t.Errorf("StatusKind(KindStatus(%q)): got %q", kind, got)
+		}
+	}
+	if got := KindStatus("foo"); got != 500 {
+		t.Errorf("KindStatus(foo): got %d, want 500", got)
+	}
+	if got := StatusKind(200); got != KindInternal {
+		t.Errorf("StatusKind(200): got %q, want %q", got, KindInternal)
+	}
+}
+
+func ExampleKindOf() {
+	err := oerrors.Wrap(NotFoundError(errors.New("user not found")), "get user")
+	fmt.Println(KindOf(err))
+	// Output: not_found
+}
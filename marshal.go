@@ -0,0 +1,185 @@
+package errorutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// MarshalVersion is the version of the JSON schema produced by Marshal.
+// Unmarshal rejects payloads with a newer version.
+const MarshalVersion = 1
+
+// MaxMarshalSize is the maximum size of a marshaled error.
+// Marshal and Unmarshal return ErrTooLarge above this size.
+var MaxMarshalSize = 64 << 10
+
+// ErrTooLarge is returned when a marshaled error exceeds MaxMarshalSize.
+var ErrTooLarge = errors.New("errorutil: marshaled error too large")
+
+// marshaledError is the JSON representation of an error.
+type marshaledError struct {
+	Version       int                        `json:"version,omitempty"`
+	Message       string                     `json:"message"`
+	Kind          Kind                       `json:"kind,omitempty"`
+	Status        int                        `json:"status,omitempty"`
+	Retryable     *bool                      `json:"retryable,omitempty"`
+	Delay         time.Duration              `json:"delay,omitempty"`
+	Code          string                     `json:"code,omitempty"`
+	PublicMessage string                     `json:"public_message,omitempty"`
+	Fields        map[string]string          `json:"fields,omitempty"`
+	Values        map[string]json.RawMessage `json:"values,omitempty"`
+	Cause         *marshaledError            `json:"cause,omitempty"`
+}
+
+// Marshal encodes an error to JSON, keeping its message and its tags (kind, HTTP status, retryable, delay, code, public message, fields
+// and values with named keys, see WithValue),
+// so that it can be passed across a service boundary (job payloads, caches, RPC...). The cause chain is not encoded.
+//
+// It returns nil if the error is nil.
+func Marshal(err error) ([]byte, error) {
+	return marshal(err, false)
+}
+
+// MarshalChain is like Marshal, but also encodes the messages and tags of the cause chain.
+func MarshalChain(err error) ([]byte, error) {
+	return marshal(err, true)
+}
+
+func marshal(err error, chain bool) ([]byte, error) {
+	if err == nil {
+		return nil, nil
+	}
+	m := newMarshaledError(err)
+	m.Version = MarshalVersion
+	if chain {
+		last := m
+		for cause := nextCause(err); cause != nil; cause = nextCause(cause) {
+			// tagging errors share the message of the error they wrap, skip them
+			if cause.Error() == last.Message {
+				continue
+			}
+			last.Cause = newMarshaledError(cause)
+			last = last.Cause
+		}
+	}
+	b, merr := json.Marshal(m)
+	if merr != nil {
+		return nil, merr
+	}
+	if len(b) > MaxMarshalSize {
+		return nil, ErrTooLarge
+	}
+	return b, nil
+}
+
+func newMarshaledError(err error) *marshaledError {
+	a := Inspector{StringFallbacks: true}.Inspect(err)
+	m := &marshaledError{
+		Message: err.Error(),
+		Kind:    StatusKind(a.Status),
+		Status:  a.Status,
+		Delay:   a.Delay,
+		Code:    a.Code,
+		Fields:  a.Fields,
+		Values:  marshalValues(err),
+	}
+	if a.Retryable || a.NotRetryable {
+		m.Retryable = &a.Retryable
+	}
+	if a.HasPublicMessage {
+		m.PublicMessage = a.PublicMessage
+	}
+	return m
+}
+
+// nextCause returns the error wrapped by err, using Cause() or Unwrap().
+func nextCause(err error) error {
+	type causer interface {
+		Cause() error
+	}
+	if cause, ok := err.(causer); ok {
+		return cause.Cause()
+	}
+	return errors.Unwrap(err)
+}
+
+// Unmarshal rebuilds an error encoded by Marshal or MarshalChain.
+// The returned error has the same message, and answers IsRetryable, IsNotRetryable, Delay,
+// HTTPStatusCode, KindOf, Code, PublicMessage, Fields and Value the same way as the original error.
+// If a payload has a kind but no status, the status is the main status of the kind (see KindStatus).
+// A status outside of the 400-599 range is replaced by StatusInternalServerError.
+//
+// If data is empty, nil is returned. If data is not a valid marshaled error,
+// the returned error describes the decoding failure.
+func Unmarshal(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if len(data) > MaxMarshalSize {
+		return ErrTooLarge
+	}
+	var m marshaledError
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("errorutil: unable to unmarshal error: %v", err)
+	}
+	if m.Version < 1 || m.Version > MarshalVersion {
+		return fmt.Errorf("errorutil: unable to unmarshal error: unsupported version %d", m.Version)
+	}
+	return m.build()
+}
+
+func (m *marshaledError) build() error {
+	base := &unmarshaledError{msg: m.Message}
+	if m.Cause != nil {
+		base.cause = m.Cause.build()
+	}
+	t := &taggedError{
+		err:           base,
+		status:        m.Status,
+		delay:         m.Delay,
+		code:          m.Code,
+		publicMessage: m.PublicMessage,
+		fields:        m.Fields,
+	}
+	for name, raw := range m.Values {
+		t.values = append(t.values, keyValue{name: name, raw: raw})
+	}
+	if t.status == 0 && m.Kind != "" {
+		// payloads written by other producers may only have a kind
+		t.status = KindStatus(m.Kind)
+	}
+	if t.status != 0 && (t.status < 400 || t.status > 599) {
+		// payloads are not trusted, a status code that is not an error would break WriteError
+		t.status = http.StatusInternalServerError
+	}
+	if t.status != 0 {
+		t.mask |= hasStatus
+	}
+	if m.Delay != 0 {
+		t.mask |= hasDelay
+	}
+	if m.PublicMessage != "" {
+		t.mask |= hasPublicMessage
+	}
+	if m.Retryable != nil {
+		t.mask |= hasRetryable
+		t.retryable = *m.Retryable
+	}
+	return t
+}
+
+type unmarshaledError struct {
+	msg   string
+	cause error
+}
+
+func (err *unmarshaledError) Error() string {
+	return err.msg
+}
+
+func (err *unmarshaledError) Cause() error {
+	return err.cause
+}
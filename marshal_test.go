@@ -0,0 +1,163 @@
+package errorutil
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestMarshal(t *testing.T) {
+	tests := []error{
+		errors.New("foo"),
+		os.ErrNotExist,
+		RetryableError(errors.New("foo")),
+		NotRetryableError(errors.New("foo")),
+		oerrors.Wrap(RetryableError(errors.New("foo")), "bar"),
+		WithDelay(RetryableError(errors.New("foo")), time.Minute),
+		NotFoundError(errors.New("foo")),
+		oerrors.Wrap(ConflictError(errors.New("foo")), "bar"),
+		httpError(429),
+		httpError(400),
+		WithCode(NotFoundError(errors.New("foo")), "user.not_found"),
+		WithPublicMessage(InvalidError(errors.New("foo")), "invalid user"),
+		NotRetryableError(WithDelay(WithCode(errors.New("foo"), "quota.exceeded"), time.Hour)),
+		WithFields(NotFoundError(errors.New("foo")), map[string]string{"id": "42"}),
+		WithDelay(errors.New("foo"), -time.Second),
+	}
+	for _, err := range tests {
+		for _, marshal := range []func(error) ([]byte, error){Marshal, MarshalChain} {
+			b, merr := marshal(err)
+			if merr != nil {
+				t.Errorf("Marshal(%q): unexpected error %v", err, merr)
+				continue
+			}
+			got := Unmarshal(b)
+			if got.Error() != err.Error() {
+				t.Errorf("Unmarshal(%s): got message %q, want %q", b, got.Error(), err.Error())
+			}
+			if IsRetryable(got) != IsRetryable(err) {
+				t.Errorf("Unmarshal(%s): got IsRetryable %v, want %v", b, IsRetryable(got), IsRetryable(err))
+			}
+			if IsNotRetryable(got) != IsNotRetryable(err) {
+				t.Errorf("Unmarshal(%s): got IsNotRetryable %v, want %v", b, IsNotRetryable(got), IsNotRetryable(err))
+			}
+			if Delay(got) != Delay(err) {
+				t.Errorf("Unmarshal(%s): got Delay %v, want %v", b, Delay(got), Delay(err))
+			}
+			if HTTPStatusCode(got) != HTTPStatusCode(err) {
+				t.Errorf("Unmarshal(%s): got HTTPStatusCode %v, want %v", b, HTTPStatusCode(got), HTTPStatusCode(err))
+			}
+			if KindOf(got) != KindOf(err) {
+				t.Errorf("Unmarshal(%s): got KindOf %q, want %q", b, KindOf(got), KindOf(err))
+			}
+			if Code(got) != Code(err) {
+				t.Errorf("Unmarshal(%s): got Code %q, want %q", b, Code(got), Code(err))
+			}
+			if !reflect.DeepEqual(Fields(got), Fields(err)) {
+				t.Errorf("Unmarshal(%s): got Fields %v, want %v", b, Fields(got), Fields(err))
+			}
+			gotMsg, gotSet := PublicMessage(got)
+			wantMsg, wantSet := PublicMessage(err)
+			if gotMsg != wantMsg || gotSet != wantSet {
+				t.Errorf("Unmarshal(%s): got PublicMessage %q, %v, want %q, %v", b, gotMsg, gotSet, wantMsg, wantSet)
+			}
+		}
+	}
+}
+
+func TestMarshalNil(t *testing.T) {
+	b, err := Marshal(nil)
+	if b != nil || err != nil {
+		t.Errorf("Marshal(nil): got %s, %v", b, err)
+	}
+	if err := Unmarshal(nil); err != nil {
+		t.Errorf("Unmarshal(nil): got %v", err)
+	}
+}
+
+func TestMarshalChain(t *testing.T) {
+	err := oerrors.Wrap(RetryableError(oerrors.Wrap(NotFoundError(errors.New("foo")), "bar")), "baz")
+	b, merr := MarshalChain(err)
+	if merr != nil {
+		t.Fatalf("MarshalChain: unexpected error %v", merr)
+	}
+	var messages []string
+	for cause := Unmarshal(b); cause != nil; cause = nextCause(cause) {
+		if len(messages) == 0 || messages[len(messages)-1] != cause.Error() {
+			messages = append(messages, cause.Error())
+		}
+	}
+	want := []string{"baz: bar: foo", "bar: foo", "foo"}
+	if strings.Join(messages, "|") != strings.Join(want, "|") {
+		t.Errorf("MarshalChain: got chain %q, want %q", messages, want)
+	}
+
+	b, _ = Marshal(err)
+	if strings.Contains(string(b), `"cause"`) {
+		t.Errorf("Marshal must not encode the cause chain, got %s", b)
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	tests := []string{
+		`foo`,
+		`{"message":"foo"}`,
+		`{"version":2,"message":"foo"}`,
+	}
+	for _, tt := range tests {
+		if err := Unmarshal([]byte(tt)); err == nil || !strings.HasPrefix(err.Error(), "errorutil: ") {
+			t.Errorf("Unmarshal(%s): got %v", tt, err)
+		}
+	}
+}
+
+func TestUnmarshalStatus(t *testing.T) {
+	tests := []struct {
+		data   string
+		status int
+		kind   Kind
+	}{
+		{`{"version":1,"message":"foo","kind":"not_found"}`, 404, KindNotFound},
+		{`{"version":1,"message":"foo","kind":"not_found","status":410}`, 410, KindNotFound},
+		{`{"version":1,"message":"foo","kind":"foo"}`, 500, KindInternal},
+		{`{"version":1,"message":"foo"}`, 500, KindInternal},
+		{`{"version":1,"message":"foo","status":42}`, 500, KindInternal},
+		{`{"version":1,"message":"foo","status":200}`, 500, KindInternal},
+		{`{"version":1,"message":"foo","status":600}`, 500, KindInternal},
+		{`{"version":1,"message":"foo","status":599}`, 599, KindInternal},
+	}
+	for _, tt := range tests {
+		err := Unmarshal([]byte(tt.data))
+		if got := HTTPStatusCode(err); got != tt.status {
+			t.Errorf("Unmarshal(%s): got HTTPStatusCode %d, want %d", tt.data, got, tt.status)
+		}
+		if got := KindOf(err); got != tt.kind {
+			t.Errorf("Unmarshal(%s): got KindOf %q, want %q", tt.data, got, tt.kind)
+		}
+	}
+}
+
+func TestMarshalTooLarge(t *testing.T) {
+	defer func(size int) { MaxMarshalSize = size }(MaxMarshalSize)
+	MaxMarshalSize = 100
+	if _, err := Marshal(errors.New(strings.Repeat("a", 100))); err != ErrTooLarge {
+		t.Errorf("Marshal: got %v, want ErrTooLarge", err)
+	}
+	if err := Unmarshal([]byte(strings.Repeat("a", 101))); err != ErrTooLarge {
+		t.Errorf("Unmarshal: got %v, want ErrTooLarge", err)
+	}
+}
+
+func ExampleMarshal() {
+	err := WithDelay(RetryableError(errors.New("database unavailable")), time.Minute)
+	b, _ := Marshal(err)
+	// store b in a job payload...
+	err = Unmarshal(b)
+	IsRetryable(err) // returns true
+	Delay(err)       // returns time.Minute
+}
@@ -0,0 +1,66 @@
+package errorutil
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives the errors seen by the package. Implementations must be safe for concurrent use.
+//
+// See the expvarmetrics sub package for an implementation based on the expvar package.
+type Metrics interface {
+	// ServerError is called by WriteError and WriteLocalizedError for each error written.
+	ServerError(err error)
+	// ClientError is called by HTTPError for each error built from a response.
+	ClientError(err error)
+	// Retry is called by backoffutil for each failed attempt that will be retried after delay.
+	Retry(err error, delay time.Duration)
+}
+
+type metricsHolder struct {
+	m Metrics
+}
+
+var metrics atomic.Value
+
+// SetMetrics sets the Metrics called by the package. A nil Metrics disables metrics, which is the default.
+func SetMetrics(m Metrics) {
+	metrics.Store(metricsHolder{m: m})
+}
+
+// GetMetrics returns the Metrics set by SetMetrics, or nil.
+func GetMetrics() Metrics {
+	h, _ := metrics.Load().(metricsHolder)
+	return h.m
+}
+
+// Labels describes an error with a small set of values, suitable as metric labels.
+type Labels struct {
+	// Kind is the kind of the error (see KindOf).
+	Kind Kind
+	// StatusClass is the class of the HTTP status code of the error, e.g. "4xx" or "5xx".
+	StatusClass string
+	// Retryable is "true" or "false" if the error is explicitly (not) retryable, "unknown" otherwise.
+	Retryable string
+	// Code is the code of the error (see Code), or an empty string.
+	Code string
+}
+
+// ErrorLabels returns the labels of an error.
+func ErrorLabels(err error) Labels {
+	a := Inspector{StringFallbacks: true}.Inspect(err)
+	l := Labels{
+		Kind:        StatusKind(a.Status),
+		StatusClass: strconv.Itoa(a.Status/100) + "xx",
+		Retryable:   "unknown",
+		Code:        a.Code,
+	}
+	switch {
+	case a.Retryable:
+		l.Retryable = "true"
+	case a.NotRetryable:
+		l.Retryable = "false"
+	}
+	return l
+}
@@ -0,0 +1,77 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestErrorLabels(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Labels
+	}{
+		{errors.New("foo"), Labels{Kind: KindInternal, StatusClass: "5xx", Retryable: "unknown"}},
+		{RetryableError(errors.New("foo")), Labels{Kind: KindInternal, StatusClass: "5xx", Retryable: "true"}},
+		{NotFoundError(errors.New("foo")), Labels{Kind: KindNotFound, StatusClass: "4xx", Retryable: "unknown"}},
+		{NotFoundError(NotRetryableError(errors.New("foo"))), Labels{Kind: KindNotFound, StatusClass: "4xx", Retryable: "false"}},
+		{WithCode(InvalidError(errors.New("foo")), "user.invalid"), Labels{Kind: KindInvalid, StatusClass: "4xx", Retryable: "unknown", Code: "user.invalid"}},
+		{httpError(429), Labels{Kind: KindRateLimited, StatusClass: "4xx", Retryable: "true"}},
+	}
+	for _, tt := range tests {
+		if got := ErrorLabels(tt.err); got != tt.want {
+			t.Errorf("ErrorLabels(%q): got %+v, want %+v", tt.err, got, tt.want)
+		}
+	}
+}
+
+type recordingMetrics struct {
+	mu             sync.Mutex
+	server, client []error
+}
+
+func (m *recordingMetrics) ServerError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.server = append(m.server, err)
+}
+
+func (m *recordingMetrics) ClientError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.client = append(m.client, err)
+}
+
+func (m *recordingMetrics) Retry(err error, delay time.Duration) {}
+
+func TestMetrics(t *testing.T) {
+	m := &recordingMetrics{}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+	if GetMetrics() != m {
+		t.Fatalf("GetMetrics: got %v, want %v", GetMetrics(), m)
+	}
+
+	WriteError(httptest.NewRecorder(), NotFoundError(errors.New("foo")))
+	WriteError(httptest.NewRecorder(), nil)
+	HTTPError(&http.Response{StatusCode: http.StatusServiceUnavailable})
+	HTTPError(&http.Response{StatusCode: http.StatusOK})
+	if len(m.server) != 1 || HTTPStatusCode(m.server[0]) != http.StatusNotFound {
+		t.Errorf("ServerError: got %v", m.server)
+	}
+	if len(m.client) != 1 || HTTPStatusCode(m.client[0]) != http.StatusServiceUnavailable {
+		t.Errorf("ClientError: got %v", m.client)
+	}
+
+	SetMetrics(nil)
+	if GetMetrics() != nil {
+		t.Errorf("GetMetrics: got %v, want nil", GetMetrics())
+	}
+	WriteError(httptest.NewRecorder(), errors.New("foo"))
+	if len(m.server) != 1 {
+		t.Errorf("ServerError: metrics must not be called once disabled")
+	}
+}
@@ -0,0 +1,69 @@
+package errorutil
+
+import (
+	"net/http"
+)
+
+// PublicMessager defines errors carrying a message that is safe to return to API clients.
+type PublicMessager interface {
+	PublicMessage() string
+}
+
+// WithPublicMessage attaches a message that is safe to return to API clients.
+// The internal error text (returned by Error()) is left untouched, so logs keep the full chain.
+// It returns nil if the error is nil.
+func WithPublicMessage(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	t := tag(err)
+	t.mask |= hasPublicMessage
+	t.publicMessage = msg
+	return t
+}
+
+// PublicMessage returns the message that can be safely returned to API clients, and
+// whether it was explicitly set with WithPublicMessage (i.e. implements PublicMessager).
+//
+// If no public message is set, a default message based on HTTPStatusCode is returned,
+// so that the internal error text is never disclosed.
+//
+// If the error is nil, an empty string is returned.
+func PublicMessage(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	type causer interface {
+		Cause() error
+	}
+
+	for e := err; e != nil; {
+		if t, ok := e.(*taggedError); ok {
+			if t.mask&hasPublicMessage != 0 {
+				return t.publicMessage, true
+			}
+			e = t.err
+			continue
+		}
+		if pub, ok := e.(PublicMessager); ok {
+			return pub.PublicMessage(), true
+		}
+		if t := classify(e); t != nil && t.mask&hasPublicMessage != 0 {
+			return t.publicMessage, true
+		}
+		cause, ok := e.(causer)
+		if !ok {
+			break
+		}
+		e = cause.Cause()
+	}
+	return defaultPublicMessage(HTTPStatusCode(err)), false
+}
+
+// defaultPublicMessage returns the message used when no public message has been set.
+func defaultPublicMessage(status int) string {
+	if msg := http.StatusText(status); msg != "" {
+		return msg
+	}
+	return http.StatusText(http.StatusInternalServerError)
+}
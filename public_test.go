@@ -0,0 +1,52 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestPublicMessage(t *testing.T) {
+	tests := []struct {
+		err     error
+		want    string
+		wantSet bool
+	}{
+		{nil, "", false},
+		{WithPublicMessage(nil, "foo"), "", false},
+
+		{errors.New("pq: relation users does not exist"), "Internal Server Error", false},
+		{NotFoundError(errors.New("foo")), "Not Found", false},
+		{oerrors.Wrap(ForbiddenError(errors.New("foo")), "bar"), "Forbidden", false},
+		{httpError(429), "Too Many Requests", false},
+
+		{WithPublicMessage(errors.New("foo"), "something went wrong"), "something went wrong", true},
+		{WithPublicMessage(NotFoundError(errors.New("foo")), "user not found"), "user not found", true},
+		{NotFoundError(WithPublicMessage(errors.New("foo"), "user not found")), "user not found", true},
+		{oerrors.Wrap(WithPublicMessage(errors.New("foo"), "user not found"), "bar"), "user not found", true},
+	}
+	for _, tt := range tests {
+		got, set := PublicMessage(tt.err)
+		if got != tt.want || set != tt.wantSet {
+			t.Errorf("PublicMessage(%q): got: %q, %v, want %q, %v", tt.err, got, set, tt.want, tt.wantSet)
+		}
+	}
+}
+
+func TestWithPublicMessage(t *testing.T) {
+	err := WithPublicMessage(NotFoundError(errors.New("sql: no rows in result set")), "user not found")
+	if err.Error() != "sql: no rows in result set" {
+		t.Errorf("WithPublicMessage must not change the error text, got %q", err.Error())
+	}
+	if HTTPStatusCode(err) != http.StatusNotFound {
+		t.Errorf("WithPublicMessage must keep the status code, got %d", HTTPStatusCode(err))
+	}
+}
+
+func ExampleWithPublicMessage() {
+	err := errors.New("dial tcp 10.0.0.12:5432: connection refused")
+	err = WithPublicMessage(err, "service temporarily unavailable")
+	PublicMessage(err) // returns "service temporarily unavailable", true
+}
@@ -0,0 +1,393 @@
+// Package journal provides a file-backed journal of pending tasks, so that delayed retries
+// scheduled by a queue.Worker survive a process restart.
+//
+// The journal is an append-only log : each record is a line holding a CRC32 checksum and a JSON document.
+// On Open, the log is replayed, and a torn or corrupted tail (e.g. after a crash during a write) is truncated.
+// Compact rewrites the log with the pending tasks only.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/objenious/errorutil"
+	"github.com/objenious/errorutil/queue"
+)
+
+// ErrClosed is returned once the journal has been closed.
+var ErrClosed = errors.New("journal: closed")
+
+// SyncMode defines when the journal file is synced to disk.
+type SyncMode int
+
+const (
+	// SyncAlways syncs the file after each record. This is the default.
+	SyncAlways SyncMode = iota
+	// SyncNever leaves it to the operating system. Records may be lost if the machine crashes,
+	// but not if the process does.
+	SyncNever
+)
+
+// Options configures a Journal.
+type Options struct {
+	// Sync defines when the journal file is synced to disk.
+	Sync SyncMode
+	// CompactThreshold is the number of obsolete records above which the journal is compacted automatically.
+	// If 0, the journal is only compacted by calling Compact.
+	CompactThreshold int
+}
+
+// Entry is a pending task.
+type Entry struct {
+	ID      string    `json:"id"`
+	Payload []byte    `json:"payload"`
+	Attempt int       `json:"attempt"`
+	NextRun time.Time `json:"next_run"`
+	// LastError is the last error returned by the task, encoded with errorutil.Marshal.
+	LastError json.RawMessage `json:"last_error,omitempty"`
+}
+
+// Err returns the last error returned by the task, if any.
+func (e Entry) Err() error {
+	return errorutil.Unmarshal(e.LastError)
+}
+
+// Handler processes the payload of a task.
+type Handler func(ctx context.Context, payload []byte) error
+
+const (
+	opPut    = "put"
+	opDelete = "del"
+)
+
+type record struct {
+	Op    string `json:"op"`
+	ID    string `json:"id,omitempty"`
+	Entry *Entry `json:"entry,omitempty"`
+}
+
+// Journal records pending tasks in a file. It must be created with Open.
+type Journal struct {
+	path string
+	opts Options
+
+	mu       sync.Mutex
+	f        *os.File
+	entries  map[string]Entry
+	obsolete int
+	err      error
+}
+
+// Open opens a journal file, creating it if needed, and replays it.
+func Open(path string, opts Options) (*Journal, error) {
+	// a left over temporary file means that the process crashed during a compaction,
+	// before the journal was replaced : the journal is still valid.
+	if err := os.Remove(path + ".tmp"); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{path: path, opts: opts, f: f, entries: map[string]Entry{}}
+	if err := j.replay(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// replay reads the log, and truncates it after the last valid record.
+func (j *Journal) replay() error {
+	r := bufio.NewReader(j.f)
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rec, ok := decode(line)
+		if !ok {
+			break
+		}
+		j.apply(rec)
+		offset += int64(len(line))
+	}
+	if err := j.f.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := j.f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+func (j *Journal) apply(rec record) {
+	switch rec.Op {
+	case opPut:
+		if _, ok := j.entries[rec.Entry.ID]; ok {
+			j.obsolete++
+		}
+		j.entries[rec.Entry.ID] = *rec.Entry
+	case opDelete:
+		if _, ok := j.entries[rec.ID]; ok {
+			j.obsolete++
+		}
+		delete(j.entries, rec.ID)
+		j.obsolete++
+	}
+}
+
+func encode(rec record) ([]byte, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	line := make([]byte, 0, len(b)+10)
+	line = append(line, fmt.Sprintf("%08x ", crc32.ChecksumIEEE(b))...)
+	line = append(line, b...)
+	return append(line, '\n'), nil
+}
+
+func decode(line []byte) (record, bool) {
+	var rec record
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	if len(line) < 9 || line[8] != ' ' {
+		return rec, false
+	}
+	sum, err := strconv.ParseUint(string(line[:8]), 16, 32)
+	if err != nil || uint32(sum) != crc32.ChecksumIEEE(line[9:]) {
+		return rec, false
+	}
+	if err := json.Unmarshal(line[9:], &rec); err != nil {
+		return rec, false
+	}
+	switch {
+	case rec.Op == opPut && rec.Entry != nil && rec.Entry.ID != "":
+	case rec.Op == opDelete && rec.ID != "":
+	default:
+		return rec, false
+	}
+	return rec, true
+}
+
+func (j *Journal) write(rec record) error {
+	line, err := encode(rec)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return ErrClosed
+	}
+	if _, err := j.f.Write(line); err != nil {
+		return err
+	}
+	if j.opts.Sync == SyncAlways {
+		if err := j.f.Sync(); err != nil {
+			return err
+		}
+	}
+	j.apply(rec)
+	if j.opts.CompactThreshold > 0 && j.obsolete >= j.opts.CompactThreshold {
+		return j.compact()
+	}
+	return nil
+}
+
+// Put records a pending task, replacing any task with the same ID.
+func (j *Journal) Put(e Entry) error {
+	if e.ID == "" {
+		return errors.New("journal: empty entry ID")
+	}
+	return j.write(record{Op: opPut, Entry: &e})
+}
+
+// Delete removes a task from the journal.
+func (j *Journal) Delete(id string) error {
+	return j.write(record{Op: opDelete, ID: id})
+}
+
+// Pending returns the pending tasks, ordered by next run time.
+func (j *Journal) Pending() []Entry {
+	j.mu.Lock()
+	entries := make([]Entry, 0, len(j.entries))
+	for _, e := range j.entries {
+		entries = append(entries, e)
+	}
+	j.mu.Unlock()
+	sort.Slice(entries, func(a, b int) bool {
+		if entries[a].NextRun.Equal(entries[b].NextRun) {
+			return entries[a].ID < entries[b].ID
+		}
+		return entries[a].NextRun.Before(entries[b].NextRun)
+	})
+	return entries
+}
+
+// Compact rewrites the journal with the pending tasks only.
+func (j *Journal) Compact() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return ErrClosed
+	}
+	return j.compact()
+}
+
+// compact writes the pending tasks to a temporary file, then atomically replaces the journal.
+func (j *Journal) compact() error {
+	tmp, err := os.OpenFile(j.path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	for _, e := range j.entries {
+		e := e
+		line, err := encode(record{Op: opPut, Entry: &e})
+		if err == nil {
+			_, err = w.Write(line)
+		}
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := os.Rename(j.path+".tmp", j.path); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := syncDir(filepath.Dir(j.path)); err != nil {
+		tmp.Close()
+		return err
+	}
+	j.f.Close()
+	f, err := os.OpenFile(j.path, os.O_RDWR, 0o600)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekEnd)
+	}
+	tmp.Close()
+	if err != nil {
+		j.f = nil
+		return err
+	}
+	j.f = f
+	j.obsolete = 0
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Close closes the journal file. It returns the first error that occurred while recording
+// the outcome of a task submitted with Submit or Replay, if any.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return ErrClosed
+	}
+	err := j.f.Close()
+	j.f = nil
+	if j.err != nil {
+		return j.err
+	}
+	return err
+}
+
+// Submit records a new task in the journal, then submits it to the worker.
+//
+// The task is removed from the journal once it succeeds or fails permanently. When it is retried,
+// the journal is updated with its attempt count, next run time and last error.
+// These decisions are the worker's (see queue.Worker.SubmitAtNotify).
+// When the worker is shut down while the task is running, its entry is kept unchanged, so that it is
+// run again after a restart.
+func (j *Journal) Submit(w *queue.Worker, payload []byte, h Handler) error {
+	e := Entry{ID: newID(), Payload: payload, NextRun: time.Now()}
+	if err := j.Put(e); err != nil {
+		return err
+	}
+	return w.SubmitAtNotify(j.task(e, h), e.NextRun, e.Attempt, j.notify(e))
+}
+
+// Replay submits the pending tasks of the journal to the worker, at their next run time.
+// It should be called on startup, before submitting new tasks.
+func (j *Journal) Replay(w *queue.Worker, h Handler) error {
+	for _, e := range j.Pending() {
+		if err := w.SubmitAtNotify(j.task(e, h), e.NextRun, e.Attempt, j.notify(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *Journal) task(e Entry, h Handler) queue.Task {
+	return func(ctx context.Context) error {
+		return h(ctx, e.Payload)
+	}
+}
+
+// notify records the decision of the worker : the entry is updated if the task is retried, deleted otherwise.
+// It is not called when the worker is shut down while the task is running, so the entry is kept to replay it.
+func (j *Journal) notify(e Entry) queue.Notify {
+	return func(err error, attempt int, d errorutil.Decision) {
+		var jerr error
+		if d.Action == errorutil.Retry {
+			e.Attempt = attempt
+			e.NextRun = time.Now().Add(d.Delay)
+			e.LastError, jerr = errorutil.Marshal(err)
+			if jerr == nil {
+				jerr = j.Put(e)
+			}
+		} else {
+			jerr = j.Delete(e.ID)
+		}
+		if jerr != nil {
+			j.mu.Lock()
+			if j.err == nil {
+				j.err = jerr
+			}
+			j.mu.Unlock()
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,262 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/objenious/errorutil"
+	"github.com/objenious/errorutil/queue"
+)
+
+func tempPath(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "journal")
+}
+
+func openTest(t *testing.T, path string, opts Options) *Journal {
+	t.Helper()
+	j, err := Open(path, opts)
+	if err != nil {
+		t.Fatalf("Open: unexpected error %v", err)
+	}
+	return j
+}
+
+func TestJournalReplay(t *testing.T) {
+	path := tempPath(t)
+	j := openTest(t, path, Options{})
+	next := time.Now().Add(time.Hour).Round(0)
+	j.Put(Entry{ID: "a", Payload: []byte("foo"), NextRun: next})
+	j.Put(Entry{ID: "b", Payload: []byte("bar")})
+	j.Put(Entry{ID: "a", Payload: []byte("foo"), Attempt: 2, NextRun: next})
+	j.Put(Entry{ID: "c", Payload: []byte("baz")})
+	j.Delete("c")
+	j.Close()
+
+	j = openTest(t, path, Options{})
+	defer j.Close()
+	entries := j.Pending()
+	if len(entries) != 2 || entries[0].ID != "b" || entries[1].ID != "a" {
+		t.Fatalf("Pending: got %+v", entries)
+	}
+	if entries[1].Attempt != 2 || !entries[1].NextRun.Equal(next) || string(entries[1].Payload) != "foo" {
+		t.Errorf("Pending: got %+v", entries[1])
+	}
+}
+
+func TestJournalTornWrite(t *testing.T) {
+	path := tempPath(t)
+	j := openTest(t, path, Options{Sync: SyncNever})
+	j.Put(Entry{ID: "a", Payload: []byte("foo")})
+	j.Put(Entry{ID: "b", Payload: []byte("bar")})
+	j.Close()
+
+	info, _ := os.Stat(path)
+	valid := info.Size()
+	// simulate a crash in the middle of a write
+	line, _ := encode(record{Op: opPut, Entry: &Entry{ID: "c"}})
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write(line[:len(line)/2])
+	f.Close()
+
+	j = openTest(t, path, Options{})
+	if entries := j.Pending(); len(entries) != 2 {
+		t.Errorf("Pending: got %d entries, want 2", len(entries))
+	}
+	if info, _ := os.Stat(path); info.Size() != valid {
+		t.Errorf("the torn record must be truncated, got size %d, want %d", info.Size(), valid)
+	}
+	j.Put(Entry{ID: "c"})
+	j.Close()
+
+	j = openTest(t, path, Options{})
+	defer j.Close()
+	if entries := j.Pending(); len(entries) != 3 {
+		t.Errorf("Pending: got %d entries, want 3", len(entries))
+	}
+}
+
+func TestJournalCorruptedRecord(t *testing.T) {
+	path := tempPath(t)
+	j := openTest(t, path, Options{})
+	j.Put(Entry{ID: "a"})
+	j.Put(Entry{ID: "b"})
+	j.Close()
+
+	b, _ := ioutil.ReadFile(path)
+	b[len(b)-5] ^= 0xff
+	ioutil.WriteFile(path, b, 0o600)
+
+	j = openTest(t, path, Options{})
+	defer j.Close()
+	if entries := j.Pending(); len(entries) != 1 || entries[0].ID != "a" {
+		t.Errorf("Pending: got %+v", entries)
+	}
+}
+
+func TestJournalCompact(t *testing.T) {
+	path := tempPath(t)
+	j := openTest(t, path, Options{CompactThreshold: 9})
+	for i := 0; i < 9; i++ {
+		j.Put(Entry{ID: "a", Attempt: i})
+	}
+	j.Put(Entry{ID: "b"})
+	info, _ := os.Stat(path)
+	before := info.Size()
+	j.Put(Entry{ID: "a", Attempt: 9})
+	info, _ = os.Stat(path)
+	if info.Size() >= before {
+		t.Errorf("the journal must be compacted, got size %d, want < %d", info.Size(), before)
+	}
+	j.Put(Entry{ID: "c"})
+	j.Close()
+
+	// simulate a crash during a compaction
+	ioutil.WriteFile(path+".tmp", []byte("garbage"), 0o600)
+
+	j = openTest(t, path, Options{})
+	defer j.Close()
+	entries := j.Pending()
+	if len(entries) != 3 {
+		t.Fatalf("Pending: got %+v", entries)
+	}
+	for _, e := range entries {
+		if e.ID == "a" && e.Attempt != 9 {
+			t.Errorf("Pending: got attempt %d, want 9", e.Attempt)
+		}
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("the temporary file must be removed")
+	}
+	if err := j.Compact(); err != nil {
+		t.Errorf("Compact: unexpected error %v", err)
+	}
+	if entries := j.Pending(); len(entries) != 3 {
+		t.Errorf("Pending: got %d entries after compaction, want 3", len(entries))
+	}
+}
+
+func TestJournalWorker(t *testing.T) {
+	path := tempPath(t)
+	opts := Options{}
+	j := openTest(t, path, opts)
+	w := queue.New(queue.Options{Disposition: errorutil.DispositionOptions{MaxAttempts: 5}})
+	handler := func(ctx context.Context, payload []byte) error {
+		switch string(payload) {
+		case "ok":
+			return nil
+		case "invalid":
+			return errorutil.InvalidError(errors.New("invalid"))
+		default:
+			return errorutil.WithDelay(errorutil.NewRetryableError("unavailable"), time.Hour)
+		}
+	}
+	for _, payload := range []string{"ok", "invalid", "later"} {
+		if err := j.Submit(w, []byte(payload), handler); err != nil {
+			t.Fatalf("Submit: unexpected error %v", err)
+		}
+	}
+	// the worker runs a single task at a time : once this one runs, the outcome of the others is recorded
+	recorded := make(chan struct{})
+	w.SubmitAt(func(ctx context.Context) error {
+		close(recorded)
+		return nil
+	}, time.Now().Add(time.Millisecond), 0)
+	<-recorded
+	// simulate a crash : the worker is stopped, the delayed retry is lost
+	w.Shutdown(context.Background())
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: unexpected error %v", err)
+	}
+
+	j = openTest(t, path, opts)
+	defer j.Close()
+	entries := j.Pending()
+	if len(entries) != 1 {
+		t.Fatalf("Pending: got %+v", entries)
+	}
+	e := entries[0]
+	if string(e.Payload) != "later" || e.Attempt != 1 || time.Until(e.NextRun) < 59*time.Minute {
+		t.Errorf("Pending: got %+v", e)
+	}
+	if !errorutil.IsRetryable(e.Err()) || errorutil.Delay(e.Err()) != time.Hour {
+		t.Errorf("Pending: got last error %v", e.Err())
+	}
+
+	// replay it, as if it was due
+	e.NextRun = time.Now()
+	j.Put(e)
+	var runs int32
+	w = queue.New(queue.Options{Disposition: errorutil.DispositionOptions{MaxAttempts: 5}, Drain: true})
+	j.Replay(w, func(ctx context.Context, payload []byte) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+	w.Shutdown(context.Background())
+	if runs != 1 {
+		t.Errorf("got %d runs, want 1", runs)
+	}
+	if entries := j.Pending(); len(entries) != 0 {
+		t.Errorf("Pending: got %+v", entries)
+	}
+}
+
+func TestJournalWorkerDisposition(t *testing.T) {
+	j := openTest(t, tempPath(t), Options{})
+	defer j.Close()
+	var deadLetters int32
+	w := queue.New(queue.Options{
+		Drain:       true,
+		Disposition: errorutil.DispositionOptions{MaxAttempts: 1},
+		DeadLetter: func(task queue.Task, err error, reason string) {
+			atomic.AddInt32(&deadLetters, 1)
+		},
+	})
+	err := j.Submit(w, []byte("foo"), func(ctx context.Context, payload []byte) error {
+		return errorutil.NewRetryableError("unavailable")
+	})
+	if err != nil {
+		t.Fatalf("Submit: unexpected error %v", err)
+	}
+	w.Shutdown(context.Background())
+	// the worker gave up after a single attempt : so does the journal
+	if deadLetters != 1 {
+		t.Errorf("got %d dead letters, want 1", deadLetters)
+	}
+	if entries := j.Pending(); len(entries) != 0 {
+		t.Errorf("Pending: got %+v", entries)
+	}
+}
+
+func TestJournalShutdown(t *testing.T) {
+	path := tempPath(t)
+	j := openTest(t, path, Options{})
+	defer j.Close()
+	w := queue.New(queue.Options{})
+	started := make(chan struct{})
+	err := j.Submit(w, []byte("foo"), func(ctx context.Context, payload []byte) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("Submit: unexpected error %v", err)
+	}
+	<-started
+	w.Shutdown(context.Background())
+	entries := j.Pending()
+	if len(entries) != 1 || string(entries[0].Payload) != "foo" || entries[0].Attempt != 0 {
+		t.Errorf("Pending: got %+v", entries)
+	}
+}
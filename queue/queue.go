@@ -0,0 +1,289 @@
+// Package queue provides an in-process worker that runs tasks with bounded concurrency,
+// and re-schedules failed tasks based on their error.
+//
+// Retryable errors (see errorutil.IsRetryable) are retried after errorutil.Delay or an exponential backoff interval,
+// using a timer heap, so that thousands of delayed retries only need a single timer.
+// Other errors are dropped or dead-lettered.
+package queue
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/objenious/errorutil"
+)
+
+// ErrClosed is returned by Submit, SubmitAt and Shutdown once the worker has been shut down.
+var ErrClosed = errors.New("queue: worker closed")
+
+// Task is a unit of work run by a Worker. The context is cancelled when the worker is shut down without draining.
+type Task func(ctx context.Context) error
+
+// Options configures a Worker.
+type Options struct {
+	// Concurrency is the maximum number of tasks run simultaneously. Defaults to 1.
+	Concurrency int
+	// Disposition configures how failed tasks are retried (see errorutil.Disposition).
+	// If Disposition.Backoff is nil, DefaultBackoff is used.
+	Disposition errorutil.DispositionOptions
+	// DeadLetter, if set, is called with tasks that failed permanently. Otherwise, they are dropped.
+	DeadLetter func(task Task, err error, reason string)
+	// Drain makes Shutdown wait for running tasks and scheduled retries to complete.
+	// Otherwise, running tasks are cancelled and scheduled retries are dropped.
+	// Tasks that return once the worker is cancelled are dropped, never dead-lettered.
+	Drain bool
+}
+
+// Worker runs submitted tasks. It must be created with New.
+type Worker struct {
+	opts   Options
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	schedule schedule
+	pending  int // scheduled and running tasks
+	closed   bool
+	drained  chan struct{}
+
+	wake  chan struct{}
+	ready chan *item
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// New starts a worker.
+func New(opts Options) *Worker {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Disposition.Backoff == nil {
+		opts.Disposition.Backoff = DefaultBackoff
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Worker{
+		opts:    opts,
+		ctx:     ctx,
+		cancel:  cancel,
+		drained: make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+		ready:   make(chan *item),
+		done:    make(chan struct{}),
+	}
+	w.wg.Add(opts.Concurrency + 1)
+	go w.scheduler()
+	for i := 0; i < opts.Concurrency; i++ {
+		go w.runner()
+	}
+	return w
+}
+
+// DefaultBackoff is an exponential backoff, like the one of backoffutil : the delay starts at 500ms,
+// and is multiplied by 1.5 at each attempt up to a minute, with a ±50% randomization.
+func DefaultBackoff(attempt int) time.Duration {
+	const maxInterval = float64(time.Minute)
+	d := float64(500 * time.Millisecond)
+	for i := 1; i < attempt && d < maxInterval; i++ {
+		d *= 1.5
+	}
+	if d > maxInterval {
+		d = maxInterval
+	}
+	return time.Duration(d * (0.5 + rand.Float64()))
+}
+
+// Submit schedules a task to be run as soon as possible.
+func (w *Worker) Submit(task Task) error {
+	return w.SubmitAt(task, time.Now(), 0)
+}
+
+// SubmitAt schedules a task to be run at a given time, as if it had already been attempted attempt times.
+// It allows resuming tasks whose previous attempts were run by another worker (e.g. before a restart).
+func (w *Worker) SubmitAt(task Task, at time.Time, attempt int) error {
+	return w.SubmitAtNotify(task, at, attempt, nil)
+}
+
+// Notify is called with the error returned by each attempt of a task, the number of attempts so far,
+// and the decision taken by the worker.
+type Notify func(err error, attempt int, d errorutil.Decision)
+
+// SubmitAtNotify is like SubmitAt, but calls notify after each attempt, before the task is re-scheduled,
+// dead-lettered or dropped. notify is not called for attempts cancelled by Shutdown.
+// It allows recording the outcome of tasks elsewhere (see the journal sub package).
+func (w *Worker) SubmitAtNotify(task Task, at time.Time, attempt int, notify Notify) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.pending++
+	heap.Push(&w.schedule, &item{task: task, at: at, attempt: attempt, notify: notify})
+	w.mu.Unlock()
+	w.signal()
+	return nil
+}
+
+// Shutdown stops the worker. New tasks are rejected.
+//
+// If Options.Drain is set, it waits for running tasks and scheduled retries to complete, or for ctx to be done,
+// in which case remaining tasks are cancelled and ctx.Err() is returned.
+// Otherwise, running tasks are cancelled, scheduled retries are dropped.
+//
+// In both cases, Shutdown returns once all running tasks have returned.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	if w.pending == 0 {
+		close(w.drained)
+	}
+	w.mu.Unlock()
+
+	var err error
+	if w.opts.Drain {
+		select {
+		case <-w.drained:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	w.cancel()
+	close(w.done)
+	w.wg.Wait()
+	return err
+}
+
+func (w *Worker) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// scheduler sends due tasks to the runners.
+func (w *Worker) scheduler() {
+	defer w.wg.Done()
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		var next *item
+		wait := time.Hour
+		w.mu.Lock()
+		if len(w.schedule) > 0 {
+			if d := time.Until(w.schedule[0].at); d <= 0 {
+				next = heap.Pop(&w.schedule).(*item)
+			} else {
+				wait = d
+			}
+		}
+		w.mu.Unlock()
+
+		if next != nil {
+			select {
+			case w.ready <- next:
+			case <-w.done:
+				return
+			}
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-w.wake:
+		case <-timer.C:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// runner runs tasks sent by the scheduler.
+func (w *Worker) runner() {
+	defer w.wg.Done()
+	for {
+		select {
+		case it := <-w.ready:
+			w.run(it)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *Worker) run(it *item) {
+	err := it.task(w.ctx)
+	it.attempt++
+	if w.ctx.Err() != nil {
+		// shutting down: the task was cancelled or must not be retried, drop it
+		w.finish()
+		return
+	}
+	d := errorutil.Disposition(err, it.attempt, w.opts.Disposition)
+	if it.notify != nil {
+		it.notify(err, it.attempt, d)
+	}
+	switch d.Action {
+	case errorutil.Retry:
+		w.mu.Lock()
+		it.at = time.Now().Add(d.Delay)
+		heap.Push(&w.schedule, it)
+		w.mu.Unlock()
+		w.signal()
+		return
+	case errorutil.DeadLetter:
+		if w.opts.DeadLetter != nil {
+			w.opts.DeadLetter(it.task, err, d.Reason)
+		}
+	}
+	w.finish()
+}
+
+// finish marks a task as completed.
+func (w *Worker) finish() {
+	w.mu.Lock()
+	w.pending--
+	if w.pending == 0 && w.closed {
+		close(w.drained)
+	}
+	w.mu.Unlock()
+}
+
+type item struct {
+	task    Task
+	at      time.Time
+	attempt int
+	notify  Notify
+}
+
+// schedule is a heap of tasks, ordered by scheduled time.
+type schedule []*item
+
+func (s schedule) Len() int           { return len(s) }
+func (s schedule) Less(i, j int) bool { return s[i].at.Before(s[j].at) }
+func (s schedule) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (s *schedule) Push(x interface{}) {
+	*s = append(*s, x.(*item))
+}
+
+func (s *schedule) Pop() interface{} {
+	old := *s
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	*s = old[:n-1]
+	return it
+}
@@ -0,0 +1,264 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/objenious/errorutil"
+)
+
+func TestWorkerConcurrency(t *testing.T) {
+	w := New(Options{Concurrency: 3, Drain: true})
+	var running, max, count int32
+	for i := 0; i < 20; i++ {
+		err := w.Submit(func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&count, 1)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Submit: unexpected error %v", err)
+		}
+	}
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: unexpected error %v", err)
+	}
+	if count != 20 {
+		t.Errorf("got %d tasks run, want 20", count)
+	}
+	if max != 3 {
+		t.Errorf("got %d concurrent tasks, want 3", max)
+	}
+}
+
+func TestWorkerRetry(t *testing.T) {
+	w := New(Options{Drain: true})
+	var attempts int32
+	start := time.Now()
+	w.Submit(func(ctx context.Context) error {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return errorutil.WithDelay(errorutil.NewRetryableError("foo"), 20*time.Millisecond)
+		}
+		return nil
+	})
+	w.Shutdown(context.Background())
+	if attempts != 3 {
+		t.Errorf("got %d attempts, want 3", attempts)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("retries must be delayed, got %v", elapsed)
+	}
+}
+
+func TestWorkerBackoff(t *testing.T) {
+	var backoffs []int
+	w := New(Options{
+		Drain: true,
+		Disposition: errorutil.DispositionOptions{
+			MaxAttempts: 3,
+			Backoff: func(attempt int) time.Duration {
+				backoffs = append(backoffs, attempt)
+				return time.Millisecond
+			},
+		},
+	})
+	w.Submit(func(ctx context.Context) error {
+		return errorutil.NewRetryableError("foo")
+	})
+	w.Shutdown(context.Background())
+	if len(backoffs) != 2 || backoffs[0] != 1 || backoffs[1] != 2 {
+		t.Errorf("got backoffs %v, want [1 2]", backoffs)
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 250 * time.Millisecond, 750 * time.Millisecond},
+		{2, 375 * time.Millisecond, 1125 * time.Millisecond},
+		{100, 30 * time.Second, 90 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 10; i++ {
+			if got := DefaultBackoff(tt.attempt); got < tt.min || got > tt.max {
+				t.Errorf("DefaultBackoff(%d): got %v, want between %v and %v", tt.attempt, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestWorkerDefaultBackoff(t *testing.T) {
+	w := New(Options{})
+	var attempts int32
+	w.Submit(func(ctx context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		return errorutil.NewRetryableError("foo")
+	})
+	time.Sleep(50 * time.Millisecond)
+	w.Shutdown(context.Background())
+	// the first retry is scheduled at least 250ms later
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("retryable errors without delay must be retried with a backoff, got %d attempts", n)
+	}
+}
+
+func TestWorkerSubmitAt(t *testing.T) {
+	var attempts []int
+	w := New(Options{
+		Drain: true,
+		Disposition: errorutil.DispositionOptions{
+			MaxAttempts: 5,
+			Backoff: func(attempt int) time.Duration {
+				attempts = append(attempts, attempt)
+				return 0
+			},
+		},
+	})
+	start := time.Now()
+	w.SubmitAt(func(ctx context.Context) error {
+		return errorutil.NewRetryableError("foo")
+	}, start.Add(20*time.Millisecond), 3)
+	w.Shutdown(context.Background())
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("task must be run at the scheduled time, got %v", elapsed)
+	}
+	if len(attempts) != 1 || attempts[0] != 4 {
+		t.Errorf("got attempts %v, want [4]", attempts)
+	}
+}
+
+func TestWorkerSubmitAtNotify(t *testing.T) {
+	w := New(Options{
+		Drain: true,
+		Disposition: errorutil.DispositionOptions{
+			MaxAttempts: 3,
+			Backoff:     func(attempt int) time.Duration { return 0 },
+		},
+	})
+	var actions []errorutil.Action
+	var attempts []int
+	w.SubmitAtNotify(func(ctx context.Context) error {
+		return errorutil.NewRetryableError("foo")
+	}, time.Now(), 0, func(err error, attempt int, d errorutil.Decision) {
+		actions = append(actions, d.Action)
+		attempts = append(attempts, attempt)
+	})
+	w.Shutdown(context.Background())
+	if want := []errorutil.Action{errorutil.Retry, errorutil.Retry, errorutil.DeadLetter}; !reflect.DeepEqual(actions, want) {
+		t.Errorf("got actions %v, want %v", actions, want)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(attempts, want) {
+		t.Errorf("got attempts %v, want %v", attempts, want)
+	}
+}
+
+func TestWorkerDeadLetter(t *testing.T) {
+	var mu sync.Mutex
+	var deadLetters []error
+	w := New(Options{
+		Concurrency: 2,
+		Drain:       true,
+		Disposition: errorutil.DispositionOptions{MaxAttempts: 2},
+		DeadLetter: func(task Task, err error, reason string) {
+			mu.Lock()
+			defer mu.Unlock()
+			deadLetters = append(deadLetters, err)
+		},
+	})
+	var attempts int32
+	w.Submit(func(ctx context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		return errorutil.NotFoundError(errors.New("foo"))
+	})
+	w.Submit(func(ctx context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		return errorutil.NewRetryableError("bar")
+	})
+	w.Shutdown(context.Background())
+	if attempts != 3 {
+		t.Errorf("got %d attempts, want 3", attempts)
+	}
+	if len(deadLetters) != 2 {
+		t.Errorf("got %d dead letters, want 2", len(deadLetters))
+	}
+}
+
+func TestWorkerShutdownCancel(t *testing.T) {
+	var deadLettered int32
+	w := New(Options{
+		DeadLetter: func(task Task, err error, reason string) {
+			atomic.AddInt32(&deadLettered, 1)
+		},
+	})
+	started := make(chan struct{})
+	var cancelled, attempts int32
+	w.Submit(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		atomic.StoreInt32(&cancelled, 1)
+		return ctx.Err()
+	})
+	w.Submit(func(ctx context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		return errorutil.WithDelay(errorutil.NewRetryableError("foo"), time.Hour)
+	})
+	<-started
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown: unexpected error %v", err)
+	}
+	if cancelled != 1 {
+		t.Errorf("running tasks must be cancelled")
+	}
+	if n := atomic.LoadInt32(&deadLettered); n != 0 {
+		t.Errorf("cancelled tasks must not be dead-lettered, got %d", n)
+	}
+	if err := w.Submit(func(ctx context.Context) error { return nil }); err != ErrClosed {
+		t.Errorf("Submit: got %v, want ErrClosed", err)
+	}
+	if err := w.Shutdown(context.Background()); err != ErrClosed {
+		t.Errorf("Shutdown: got %v, want ErrClosed", err)
+	}
+}
+
+func TestWorkerShutdownDrainTimeout(t *testing.T) {
+	w := New(Options{Drain: true})
+	w.Submit(func(ctx context.Context) error {
+		return errorutil.WithDelay(errorutil.NewRetryableError("foo"), time.Hour)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := w.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown: got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func ExampleWorker() {
+	w := New(Options{
+		Concurrency: 10,
+		Disposition: errorutil.DispositionOptions{MaxAttempts: 5},
+		Drain:       true,
+	})
+	w.Submit(func(ctx context.Context) error {
+		// retried after an hour
+		return errorutil.WithDelay(errorutil.NewRetryableError("quota exceeded"), time.Hour)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	w.Shutdown(ctx)
+}
@@ -0,0 +1,169 @@
+package errorutil
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limit headers, written by WriteError and SetQuotaHeaders, and read by HTTPError.
+// Both the RateLimit-* headers of the IETF draft and the widespread X-RateLimit-* headers are supported.
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	// HeaderRateLimitReset is the number of seconds until the quota resets.
+	HeaderRateLimitReset = "RateLimit-Reset"
+	// HeaderRateLimitResource is the name of the quota.
+	HeaderRateLimitResource   = "X-RateLimit-Resource"
+	HeaderXRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderXRateLimitRemaining = "X-RateLimit-Remaining"
+	// HeaderXRateLimitReset is the time at which the quota resets, in UTC epoch seconds.
+	HeaderXRateLimitReset = "X-RateLimit-Reset"
+)
+
+// QuotaError is a "too many requests" error, returned when a quota is exhausted.
+// It is retryable after the reset of the quota (see Delay), unless its cause is not retryable (see Retryable),
+// and its status code is StatusTooManyRequests.
+type QuotaError struct {
+	// Quota is the name of the quota, if any.
+	Quota string
+	// Limit is the number of requests allowed by the quota, or -1 if unknown.
+	Limit int
+	// Remaining is the number of requests left, or -1 if unknown.
+	Remaining int
+	// Reset is the time at which the quota resets, or the zero time if unknown.
+	Reset time.Time
+	// Err is the cause of the error, if any.
+	Err error
+}
+
+// NewQuotaError returns a QuotaError for an exhausted quota.
+func NewQuotaError(quota string, limit int, reset time.Time) *QuotaError {
+	return &QuotaError{Quota: quota, Limit: limit, Remaining: 0, Reset: reset}
+}
+
+func (err *QuotaError) Error() string {
+	msg := "quota exceeded"
+	if err.Quota != "" {
+		msg = "quota " + err.Quota + " exceeded"
+	}
+	if err.Err != nil {
+		msg += ": " + err.Err.Error()
+	}
+	return msg
+}
+
+func (err *QuotaError) HTTPStatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+// Retryable returns true, unless Err implements Retryabler and is not retryable
+// (e.g. a response with rate limit headers and a "X-Error-Retryable: false" header, see HTTPError).
+func (err *QuotaError) Retryable() bool {
+	if r, ok := err.Err.(Retryabler); ok {
+		return r.Retryable()
+	}
+	return true
+}
+
+// Delay returns the duration until the reset of the quota.
+func (err *QuotaError) Delay() time.Duration {
+	if err.Reset.IsZero() {
+		return 0
+	}
+	if d := time.Until(err.Reset); d > 0 {
+		return d
+	}
+	return 0
+}
+
+func (err *QuotaError) Cause() error {
+	return err.Err
+}
+
+// Quota returns the QuotaError found in the chain of an error.
+//
+// If the error is nil or has no QuotaError, nil is returned.
+func Quota(err error) *QuotaError {
+	type causer interface {
+		Cause() error
+	}
+
+	for err != nil {
+		if q, ok := err.(*QuotaError); ok {
+			return q
+		}
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return nil
+}
+
+// SetQuotaHeaders sets the rate limit headers describing a quota. It is called by WriteError for QuotaError errors,
+// and may be used on successful responses too. Unknown values (negative limit or remaining, zero reset) are not set.
+func SetQuotaHeaders(h http.Header, q *QuotaError) {
+	if q.Quota != "" {
+		h.Set(HeaderRateLimitResource, q.Quota)
+	}
+	if q.Limit >= 0 {
+		h.Set(HeaderRateLimitLimit, strconv.Itoa(q.Limit))
+		h.Set(HeaderXRateLimitLimit, strconv.Itoa(q.Limit))
+	}
+	if q.Remaining >= 0 {
+		h.Set(HeaderRateLimitRemaining, strconv.Itoa(q.Remaining))
+		h.Set(HeaderXRateLimitRemaining, strconv.Itoa(q.Remaining))
+	}
+	if !q.Reset.IsZero() {
+		h.Set(HeaderRateLimitReset, strconv.FormatInt(int64((q.Delay()+time.Second-1)/time.Second), 10))
+		h.Set(HeaderXRateLimitReset, strconv.FormatInt(q.Reset.Unix(), 10))
+	}
+}
+
+// newQuotaError builds a QuotaError from the rate limit headers of a response, or returns nil if there are none.
+// retryAfter is used when no reset time is given.
+func newQuotaError(h http.Header, err error, retryAfter time.Duration) *QuotaError {
+	q := &QuotaError{
+		Quota:     h.Get(HeaderRateLimitResource),
+		Limit:     parseRateLimit(h, HeaderRateLimitLimit, HeaderXRateLimitLimit),
+		Remaining: parseRateLimit(h, HeaderRateLimitRemaining, HeaderXRateLimitRemaining),
+		Err:       err,
+	}
+	if reset := parseRateLimit(h, HeaderRateLimitReset); reset >= 0 {
+		q.Reset = time.Now().Add(time.Duration(reset) * time.Second)
+	} else if reset := parseRateLimit(h, HeaderXRateLimitReset); reset >= 0 {
+		if reset >= 1e9 {
+			q.Reset = time.Unix(int64(reset), 0)
+		} else {
+			// some servers send the number of seconds until the reset
+			q.Reset = time.Now().Add(time.Duration(reset) * time.Second)
+		}
+	}
+	if q.Limit < 0 && q.Remaining < 0 && q.Reset.IsZero() {
+		return nil
+	}
+	if q.Reset.IsZero() && retryAfter > 0 {
+		q.Reset = time.Now().Add(retryAfter)
+	}
+	return q
+}
+
+// parseRateLimit returns the value of the first set header, ignoring parameters (e.g. "100;w=60"), or -1.
+func parseRateLimit(h http.Header, keys ...string) int {
+	for _, key := range keys {
+		value := h.Get(key)
+		if value == "" {
+			continue
+		}
+		if i := strings.IndexAny(value, ",;"); i >= 0 {
+			value = value[:i]
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return -1
+}
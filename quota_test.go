@@ -0,0 +1,138 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestQuotaError(t *testing.T) {
+	reset := time.Now().Add(time.Minute)
+	err := oerrors.Wrap(NewQuotaError("search", 100, reset), "bar")
+	if !IsRetryable(err) || HTTPStatusCode(err) != http.StatusTooManyRequests {
+		t.Errorf("QuotaError: got retryable %v, status %d", IsRetryable(err), HTTPStatusCode(err))
+	}
+	if d := Delay(err); d <= 59*time.Second || d > time.Minute {
+		t.Errorf("Delay: got %v, want about a minute", d)
+	}
+	if q := Quota(err); q == nil || q.Quota != "search" || q.Limit != 100 || q.Remaining != 0 {
+		t.Errorf("Quota: got %+v", q)
+	}
+	if msg := err.Error(); msg != "bar: quota search exceeded" {
+		t.Errorf("Error: got %q", msg)
+	}
+	if Delay(NewQuotaError("", 1, time.Now().Add(-time.Second))) != 0 || Delay(&QuotaError{}) != 0 {
+		t.Errorf("Delay: expected 0 for past or unknown resets")
+	}
+	if Quota(errors.New("foo")) != nil {
+		t.Errorf("Quota: expected nil")
+	}
+	if msg := (&QuotaError{Err: errors.New("foo")}).Error(); msg != "quota exceeded: foo" {
+		t.Errorf("Error: got %q", msg)
+	}
+}
+
+func TestQuotaRoundTrip(t *testing.T) {
+	reset := time.Now().Add(30 * time.Second).Truncate(time.Second)
+	w := httptest.NewRecorder()
+	WriteError(w, WithCode(NewQuotaError("search", 100, reset), "quota.search"))
+	resp := w.Result()
+	for key, want := range map[string]string{
+		HeaderRateLimitResource:   "search",
+		HeaderRateLimitLimit:      "100",
+		HeaderXRateLimitLimit:     "100",
+		HeaderRateLimitRemaining:  "0",
+		HeaderXRateLimitRemaining: "0",
+		HeaderXRateLimitReset:     strconv.FormatInt(reset.Unix(), 10),
+		HeaderRetryAfter:          resp.Header.Get(HeaderRateLimitReset),
+	} {
+		if got := resp.Header.Get(key); got != want {
+			t.Errorf("WriteError: got %s %q, want %q", key, got, want)
+		}
+	}
+
+	err := HTTPError(resp)
+	q := Quota(err)
+	if q == nil || q.Quota != "search" || q.Limit != 100 || q.Remaining != 0 {
+		t.Fatalf("HTTPError: got %#v", err)
+	}
+	if d := q.Reset.Sub(reset); d < -time.Second || d > time.Second {
+		t.Errorf("HTTPError: got reset %v, want %v", q.Reset, reset)
+	}
+	if Code(err) != "quota.search" || !IsRetryable(err) || Delay(err) <= 0 {
+		t.Errorf("HTTPError: got code %q, retryable %v, delay %v", Code(err), IsRetryable(err), Delay(err))
+	}
+}
+
+func TestHTTPErrorQuotaRetryable(t *testing.T) {
+	tests := []struct {
+		value     string
+		retryable bool
+	}{
+		{"", true},
+		{"true", true},
+		{"false", false},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set(HeaderRateLimitRemaining, "0")
+		if tt.value != "" {
+			h.Set(HeaderRetryable, tt.value)
+		}
+		err := HTTPError(&http.Response{StatusCode: http.StatusTooManyRequests, Header: h})
+		if Quota(err) == nil {
+			t.Fatalf("HTTPError(%v): expected a QuotaError", h)
+		}
+		if IsRetryable(err) != tt.retryable || IsNotRetryable(err) == tt.retryable {
+			t.Errorf("HTTPError(%v): got retryable %v, not retryable %v", h, IsRetryable(err), IsNotRetryable(err))
+		}
+	}
+}
+
+func TestHTTPErrorQuotaHeaders(t *testing.T) {
+	epoch := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		header             http.Header
+		quota              bool
+		limit, remaining   int
+		delayMin, delayMax time.Duration
+	}{
+		{http.Header{}, false, 0, 0, 0, 0},
+		{http.Header{"Ratelimit-Limit": {"100, 100;w=60"}, "Ratelimit-Remaining": {"0"}, "Ratelimit-Reset": {"10"}}, true, 100, 0, 9 * time.Second, 10 * time.Second},
+		{http.Header{"X-Ratelimit-Limit": {"5000"}, "X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {strconv.FormatInt(epoch, 10)}}, true, 5000, 0, 59 * time.Minute, time.Hour},
+		{http.Header{"X-Ratelimit-Limit": {"10"}, "X-Ratelimit-Reset": {"20"}}, true, 10, -1, 19 * time.Second, 20 * time.Second},
+		{http.Header{"X-Ratelimit-Remaining": {"0"}, "Retry-After": {"5"}}, true, -1, 0, 4 * time.Second, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		err := HTTPError(&http.Response{StatusCode: http.StatusTooManyRequests, Header: tt.header})
+		q := Quota(err)
+		if (q != nil) != tt.quota {
+			t.Errorf("HTTPError(%v): got quota %+v", tt.header, q)
+			continue
+		}
+		if q == nil {
+			continue
+		}
+		if q.Limit != tt.limit || q.Remaining != tt.remaining {
+			t.Errorf("HTTPError(%v): got limit %d, remaining %d", tt.header, q.Limit, q.Remaining)
+		}
+		if d := Delay(err); d < tt.delayMin || d > tt.delayMax {
+			t.Errorf("HTTPError(%v): got delay %v, want between %v and %v", tt.header, d, tt.delayMin, tt.delayMax)
+		}
+	}
+	if Quota(HTTPError(&http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"X-Ratelimit-Remaining": {"0"}}})) != nil {
+		t.Errorf("HTTPError: only StatusTooManyRequests responses are quota errors")
+	}
+}
+
+func ExampleSetQuotaHeaders() {
+	var w http.ResponseWriter
+	reset := time.Now().Truncate(time.Minute).Add(time.Minute)
+	q := &QuotaError{Quota: "search", Limit: 100, Remaining: 42, Reset: reset}
+	SetQuotaHeaders(w.Header(), q) // informs the client of its quota on successful responses
+}
@@ -0,0 +1,222 @@
+package errorutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers used to propagate the classification of an error between services.
+// WriteError sets them, HTTPError reads them.
+const (
+	// HeaderRetryable is set to "true" or "false" if the error is explicitly (not) retryable.
+	HeaderRetryable = "X-Error-Retryable"
+	// HeaderCode is set to the code of the error.
+	HeaderCode = "X-Error-Code"
+	// HeaderRetryAfter is set to the delay of the error, in seconds.
+	HeaderRetryAfter = "Retry-After"
+)
+
+// problem is a RFC 7807 problem details object.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code,omitempty"`
+	// Errors are the violations of a ValidationError.
+	Errors []Violation `json:"errors,omitempty"`
+}
+
+// WriteError writes an error response to a http.ResponseWriter.
+//
+// The status code is set using HTTPStatusCode, or StatusInternalServerError if it is outside of the 400-599 range.
+// The body is a RFC 7807 problem (application/problem+json),
+// whose detail is the public message of the error (see PublicMessage). The internal error text is never written.
+// The violations of a ValidationError are written in the "errors" extension member.
+//
+// The classification of the error is propagated using the HeaderRetryable, HeaderCode and HeaderRetryAfter headers,
+// so that HTTPError rebuilds an error with the same tags on the client side.
+// The authentication challenges of the error (see Challenges) are set in WWW-Authenticate headers,
+// the current version of the resource (see Versions) in the ETag header, and the quota of a QuotaError in rate limit headers.
+//
+// If the error is nil, nothing is written.
+func WriteError(w http.ResponseWriter, err error) {
+	if err == nil {
+		return
+	}
+	msg, _ := PublicMessage(err)
+	writeError(w, err, msg, "")
+}
+
+// Localizer translates the public message of errors.
+type Localizer interface {
+	// Localize returns the message of the error in the best language matching an Accept-Language header,
+	// and this language. ok is false if no translation was found.
+	Localize(err error, acceptLanguage string) (msg, lang string, ok bool)
+}
+
+// WriteLocalizedError is like WriteError, but the public message is translated by l according to the
+// Accept-Language header of the request, and the Content-Language header is set.
+// If no translation is found, the public message is used.
+func WriteLocalizedError(w http.ResponseWriter, r *http.Request, err error, l Localizer) {
+	if err == nil {
+		return
+	}
+	msg, lang, ok := l.Localize(err, r.Header.Get("Accept-Language"))
+	if !ok {
+		msg, _ = PublicMessage(err)
+		lang = ""
+	}
+	writeError(w, err, msg, lang)
+}
+
+func writeError(w http.ResponseWriter, err error, msg, lang string) {
+	if m := GetMetrics(); m != nil {
+		m.ServerError(err)
+	}
+	a := Inspector{StringFallbacks: true}.Inspect(err)
+	if a.Status < 400 || a.Status > 599 {
+		// not an error status code : WriteHeader would panic, or write a successful response
+		if !a.HasPublicMessage && msg == defaultPublicMessage(a.Status) {
+			msg = defaultPublicMessage(http.StatusInternalServerError)
+		}
+		a.Status = http.StatusInternalServerError
+	}
+	p := problem{
+		Type:   "about:blank",
+		Title:  defaultPublicMessage(a.Status),
+		Status: a.Status,
+		Detail: msg,
+		Code:   a.Code,
+		Errors: Violations(err),
+	}
+
+	h := w.Header()
+	switch {
+	case a.Retryable:
+		h.Set(HeaderRetryable, "true")
+	case a.NotRetryable:
+		h.Set(HeaderRetryable, "false")
+	}
+	if p.Code != "" {
+		h.Set(HeaderCode, p.Code)
+	}
+	if delay := a.Delay; delay > 0 {
+		h.Set(HeaderRetryAfter, strconv.FormatInt(int64((delay+time.Second-1)/time.Second), 10))
+	}
+	if q := Quota(err); q != nil {
+		SetQuotaHeaders(h, q)
+	}
+	if current, _, ok := Versions(err); ok && current != "" {
+		h.Set("ETag", entityTag(current))
+	}
+	for _, challenge := range Challenges(err) {
+		h.Add("WWW-Authenticate", challenge)
+	}
+	if lang != "" {
+		h.Set("Content-Language", lang)
+	}
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(a.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// responseError is a httpError carrying the classification propagated through response headers.
+type responseError struct {
+	httpError
+	retryable  bool
+	delay      time.Duration
+	code       string
+	challenges []string
+	etag       string
+}
+
+func newResponseError(resp *http.Response) error {
+	err := newHeaderError(resp)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if q := newQuotaError(resp.Header, err, ParseRetryAfter(resp.Header.Get(HeaderRetryAfter))); q != nil {
+			return q
+		}
+	}
+	return err
+}
+
+// newHeaderError builds an error from the status code and the classification headers of a response.
+func newHeaderError(resp *http.Response) error {
+	err := httpError(resp.StatusCode)
+	h := resp.Header
+	var etag string
+	if resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusPreconditionFailed {
+		etag = h.Get("ETag")
+	}
+	if h.Get(HeaderRetryable) == "" && h.Get(HeaderCode) == "" && h.Get(HeaderRetryAfter) == "" && h.Get("WWW-Authenticate") == "" && etag == "" {
+		return err
+	}
+	rerr := &responseError{
+		httpError:  err,
+		retryable:  err.Retryable(),
+		delay:      ParseRetryAfter(h.Get(HeaderRetryAfter)),
+		code:       h.Get(HeaderCode),
+		challenges: h.Values("WWW-Authenticate"),
+		etag:       etag,
+	}
+	switch strings.ToLower(h.Get(HeaderRetryable)) {
+	case "true":
+		rerr.retryable = true
+	case "false":
+		rerr.retryable = false
+	}
+	if etag != "" {
+		return responseVersionError{rerr}
+	}
+	return rerr
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, either as a number of seconds or as a HTTP date.
+// It returns 0 if the value is empty, invalid, or not in the future.
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func (err *responseError) Retryable() bool {
+	return err.retryable
+}
+
+func (err *responseError) Delay() time.Duration {
+	return err.delay
+}
+
+func (err *responseError) Code() string {
+	return err.code
+}
+
+func (err *responseError) Challenges() []string {
+	return err.challenges
+}
+
+// responseVersionError is a responseError carrying the ETag of a StatusConflict or StatusPreconditionFailed response.
+type responseVersionError struct {
+	*responseError
+}
+
+func (err responseVersionError) Versions() (current, expected string) {
+	return err.etag, ""
+}
@@ -0,0 +1,138 @@
+package errorutil
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestWriteErrorRoundTrip(t *testing.T) {
+	tests := []error{
+		errors.New("foo"),
+		RetryableError(errors.New("foo")),
+		NotRetryableError(errors.New("foo")),
+		NotFoundError(errors.New("foo")),
+		oerrors.Wrap(ConflictError(errors.New("foo")), "bar"),
+		WithCode(NotFoundError(errors.New("foo")), "user.not_found"),
+		WithDelay(RetryableError(errors.New("foo")), 30*time.Second),
+		NotRetryableError(httpError(http.StatusServiceUnavailable)),
+		RetryableError(httpError(http.StatusBadRequest)),
+		httpError(429),
+	}
+	for _, err := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			WriteError(w, err)
+		}))
+		resp, herr := http.Get(srv.URL)
+		if herr != nil {
+			t.Fatalf("unexpected error %v", herr)
+		}
+		resp.Body.Close()
+		srv.Close()
+
+		got := HTTPError(resp)
+		if HTTPStatusCode(got) != HTTPStatusCode(err) {
+			t.Errorf("HTTPError(WriteError(%q)): got HTTPStatusCode %v, want %v", err, HTTPStatusCode(got), HTTPStatusCode(err))
+		}
+		// unclassified errors keep the default retryability of their status code
+		if (IsRetryable(err) || IsNotRetryable(err)) && IsRetryable(got) != IsRetryable(err) {
+			t.Errorf("HTTPError(WriteError(%q)): got IsRetryable %v, want %v", err, IsRetryable(got), IsRetryable(err))
+		}
+		if Delay(got) != Delay(err) {
+			t.Errorf("HTTPError(WriteError(%q)): got Delay %v, want %v", err, Delay(got), Delay(err))
+		}
+		if Code(got) != Code(err) {
+			t.Errorf("HTTPError(WriteError(%q)): got Code %q, want %q", err, Code(got), Code(err))
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	err := errors.New("pq: password authentication failed for user admin")
+	err = WithCode(WithPublicMessage(ForbiddenError(err), "access denied"), "auth.denied")
+	w := httptest.NewRecorder()
+	WriteError(w, err)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("WriteError: got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("WriteError: got content type %q", ct)
+	}
+	var p problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("WriteError: invalid body %v", err)
+	}
+	want := problem{Type: "about:blank", Title: "Forbidden", Status: http.StatusForbidden, Detail: "access denied", Code: "auth.denied"}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("WriteError: got %+v, want %+v", p, want)
+	}
+
+	w = httptest.NewRecorder()
+	WriteError(w, nil)
+	if w.Body.Len() != 0 || len(w.Header()) != 0 {
+		t.Errorf("WriteError(nil) must not write anything")
+	}
+}
+
+func TestWriteErrorInvalidStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		detail string
+	}{
+		{WithHTTPStatus(errors.New("foo"), http.StatusOK), "Internal Server Error"},
+		{WithHTTPStatus(errors.New("foo"), 42), "Internal Server Error"},
+		{WithPublicMessage(WithHTTPStatus(errors.New("foo"), 600), "oops"), "oops"},
+		{Unmarshal([]byte(`{"version":1,"message":"foo","status":42}`)), "Internal Server Error"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		WriteError(w, tt.err)
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("WriteError(%v): got status %d, want %d", tt.err, w.Code, http.StatusInternalServerError)
+		}
+		var p problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("WriteError(%v): invalid body %v", tt.err, err)
+		}
+		want := problem{Type: "about:blank", Title: "Internal Server Error", Status: http.StatusInternalServerError, Detail: tt.detail}
+		if !reflect.DeepEqual(p, want) {
+			t.Errorf("WriteError(%v): got %+v, want %+v", tt.err, p, want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"foo", 0},
+		{"-1", 0},
+		{"0", 0},
+		{"120", 2 * time.Minute},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		got := ParseRetryAfter(tt.value)
+		if got != tt.want {
+			t.Errorf("ParseRetryAfter(%q): got %v, want %v", tt.value, got, tt.want)
+		}
+	}
+	if got := ParseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("ParseRetryAfter(date): got %v", got)
+	}
+}
+
+func ExampleWriteError() {
+	http.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		err := errors.New("sql: no rows in result set")
+		WriteError(w, WithCode(NotFoundError(err), "user.not_found"))
+	})
+}
@@ -20,9 +20,19 @@ func IsRetryable(err error) bool {
 	}
 
 	for err != nil {
+		if t, ok := err.(*taggedError); ok {
+			if t.mask&hasRetryable != 0 {
+				return t.retryable
+			}
+			err = t.err
+			continue
+		}
 		if retry, ok := err.(Retryabler); ok {
 			return retry.Retryable()
 		}
+		if t := classify(err); t != nil && t.mask&hasRetryable != 0 {
+			return t.retryable
+		}
 		cause, ok := err.(causer)
 		if !ok {
 			break
@@ -41,9 +51,19 @@ func IsNotRetryable(err error) bool {
 	}
 
 	for err != nil {
+		if t, ok := err.(*taggedError); ok {
+			if t.mask&hasRetryable != 0 {
+				return !t.retryable
+			}
+			err = t.err
+			continue
+		}
 		if retry, ok := err.(Retryabler); ok {
 			return !retry.Retryable()
 		}
+		if t := classify(err); t != nil && t.mask&hasRetryable != 0 {
+			return !t.retryable
+		}
 		cause, ok := err.(causer)
 		if !ok {
 			break
@@ -58,7 +78,11 @@ func RetryableError(err error) error {
 	if err == nil {
 		return nil
 	}
-	return &retryableError{err: err}
+	t := tag(err)
+	t.mask |= hasRetryable | hasStatus
+	t.retryable = true
+	t.status = http.StatusInternalServerError
+	return t
 }
 
 // NotRetryableError marks an error as NOT retryable. It returns nil if the error is nil.
@@ -66,7 +90,11 @@ func NotRetryableError(err error) error {
 	if err == nil {
 		return nil
 	}
-	return &notRetryableError{err: err}
+	t := tag(err)
+	t.mask |= hasRetryable | hasStatus
+	t.retryable = false
+	t.status = http.StatusInternalServerError
+	return t
 }
 
 // NewRetryableError returns a retryable error that formats as the given text.
@@ -79,43 +107,3 @@ func NewRetryableError(text string) error {
 func NewRetryableErrorf(format string, args ...interface{}) error {
 	return RetryableError(fmt.Errorf(format, args...))
 }
-
-type retryableError struct {
-	err error
-}
-
-func (err *retryableError) Error() string {
-	return err.err.Error()
-}
-
-func (err *retryableError) Retryable() bool {
-	return true
-}
-
-func (err *retryableError) HTTPStatusCode() int {
-	return http.StatusInternalServerError
-}
-
-func (err *retryableError) Cause() error {
-	return err.err
-}
-
-type notRetryableError struct {
-	err error
-}
-
-func (err *notRetryableError) Error() string {
-	return err.err.Error()
-}
-
-func (err *notRetryableError) Retryable() bool {
-	return false
-}
-
-func (err *notRetryableError) HTTPStatusCode() int {
-	return http.StatusInternalServerError
-}
-
-func (err *notRetryableError) Cause() error {
-	return err.err
-}
@@ -0,0 +1,81 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	oerrors "github.com/objenious/errors"
+	"github.com/objenious/errorutil"
+)
+
+// mysqlError mimics *mysql.MySQLError (go-sql-driver/mysql).
+type mysqlError struct {
+	Number   uint16
+	SQLState [5]byte
+	Message  string
+}
+
+func (err *mysqlError) Error() string { return fmt.Sprintf("Error %d: %s", err.Number, err.Message) }
+
+// numberError exposes its error number with a method.
+type numberError int
+
+func (err numberError) Error() string { return "foo" }
+func (err numberError) Number() int   { return int(err) }
+
+func TestClassifyMySQL(t *testing.T) {
+	tests := []struct {
+		err          error
+		number       int
+		status       int
+		retryable    bool
+		notRetryable bool
+	}{
+		{&mysqlError{Number: 1213, Message: "Deadlock found when trying to get lock"}, 1213, http.StatusInternalServerError, true, false},
+		{&mysqlError{Number: 1205, Message: "Lock wait timeout exceeded"}, 1205, http.StatusInternalServerError, true, false},
+		{&mysqlError{Number: 1062, Message: "Duplicate entry"}, 1062, http.StatusConflict, false, true},
+		{oerrors.Wrap(&mysqlError{Number: 1062}, "bar"), 1062, http.StatusConflict, false, true},
+		{&mysqlError{Number: 1452, Message: "Cannot add or update a child row"}, 1452, http.StatusBadRequest, false, true},
+		{&mysqlError{Number: 2006, Message: "MySQL server has gone away"}, 2006, http.StatusServiceUnavailable, true, false},
+		{numberError(2013), 2013, http.StatusServiceUnavailable, true, false},
+		{oerrors.Wrap(numberError(2013), "bar"), 2013, http.StatusServiceUnavailable, true, false},
+		{&mysqlError{Number: 1146, Message: "Table doesn't exist"}, 1146, http.StatusInternalServerError, false, false},
+		{errors.New("foo"), 0, http.StatusInternalServerError, false, false},
+		{errorutil.NotFoundError(&mysqlError{Number: 1062}), 1062, http.StatusNotFound, false, true},
+	}
+	for _, tt := range tests {
+		if got := Number(tt.err); got != tt.number {
+			t.Errorf("Number(%#v): got %d, want %d", tt.err, got, tt.number)
+		}
+		if got := errorutil.HTTPStatusCode(tt.err); got != tt.status {
+			t.Errorf("HTTPStatusCode(%#v): got %d, want %d", tt.err, got, tt.status)
+		}
+		if got := errorutil.IsRetryable(tt.err); got != tt.retryable {
+			t.Errorf("IsRetryable(%#v): got %v, want %v", tt.err, got, tt.retryable)
+		}
+		if got := errorutil.IsNotRetryable(tt.err); got != tt.notRetryable {
+			t.Errorf("IsNotRetryable(%#v): got %v, want %v", tt.err, got, tt.notRetryable)
+		}
+	}
+}
+
+func TestRunInTxMySQLDeadlock(t *testing.T) {
+	fake := &fakeDB{commit: func(n int) error {
+		if n == 1 {
+			return &mysqlError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+		}
+		return nil
+	}}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+	if err := RunInTx(context.Background(), db, nil, update); err != nil {
+		t.Errorf("RunInTx: unexpected error %v", err)
+	}
+	if fake.begins != 2 || fake.commits != 1 {
+		t.Errorf("RunInTx: got %d begins, %d commits, want 2, 1", fake.begins, fake.commits)
+	}
+}
@@ -0,0 +1,255 @@
+// Package sqlutil classifies database errors by their SQLSTATE code or MySQL error number, without importing any driver.
+//
+// Importing the package registers a classifier (see errorutil.RegisterClassifier), so that errorutil.HTTPStatusCode,
+// errorutil.IsRetryable... understand driver errors :
+//
+//	import _ "github.com/objenious/errorutil/sqlutil"
+//
+// The SQLSTATE code is read from a SQLState() method (pgx, lib/pq), or from a string field named Code (lib/pq).
+// The MySQL error number is read from an integer field or method named Number (go-sql-driver/mysql).
+//
+// Postgres errors are classified as follows :
+//
+//	40001, 40P01 (serialization failure, deadlock)           retryable
+//	23505 (unique violation)                                 409 Conflict, not retryable
+//	23503, 23502, class 22 (constraint violations, data)     400 Bad Request, not retryable
+//	57014 (query canceled)                                   499 Canceled (errorutil.StatusCanceled)
+//	class 08, class 53 (connection, insufficient resources)  503 Service Unavailable, retryable
+//
+// MySQL errors are classified as follows :
+//
+//	1213, 1205 (deadlock, lock wait timeout)                 retryable
+//	1062 (duplicate entry)                                   409 Conflict, not retryable
+//	1452 (foreign key constraint)                            400 Bad Request, not retryable
+//	2006, 2013 (server gone away, connection lost)           503 Service Unavailable, retryable
+package sqlutil
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/objenious/errorutil"
+)
+
+func init() {
+	errorutil.RegisterClassifier(Classify)
+}
+
+// Classify returns the tags of a database error, based on its SQLSTATE code or MySQL error number. It returns nil
+// if err has neither, or if they are not classified. Only err is checked, not its causes.
+func Classify(err error) []errorutil.TagOption {
+	t := errorTypeOf(err)
+	if state := sqlState(err, t); state != "" {
+		return classifyState(state)
+	}
+	if number := mysqlNumber(err, t); number != 0 {
+		return classifyNumber(number)
+	}
+	return nil
+}
+
+func classifyNumber(number int) []errorutil.TagOption {
+	switch number {
+	case 1213, 1205:
+		return []errorutil.TagOption{errorutil.Retryable()}
+	case 1062:
+		return []errorutil.TagOption{errorutil.Status(http.StatusConflict), errorutil.NotRetryable()}
+	case 1452:
+		return []errorutil.TagOption{errorutil.Status(http.StatusBadRequest), errorutil.NotRetryable()}
+	case 2006, 2013:
+		return []errorutil.TagOption{errorutil.Status(http.StatusServiceUnavailable), errorutil.Retryable()}
+	}
+	return nil
+}
+
+func classifyState(state string) []errorutil.TagOption {
+	switch state {
+	case "40001", "40P01":
+		return []errorutil.TagOption{errorutil.Retryable()}
+	case "23505":
+		return []errorutil.TagOption{errorutil.Status(http.StatusConflict), errorutil.NotRetryable()}
+	case "23503", "23502":
+		return []errorutil.TagOption{errorutil.Status(http.StatusBadRequest), errorutil.NotRetryable()}
+	case "57014":
+		return []errorutil.TagOption{errorutil.Status(errorutil.StatusCanceled)}
+	}
+	switch state[:2] {
+	case "22":
+		return []errorutil.TagOption{errorutil.Status(http.StatusBadRequest), errorutil.NotRetryable()}
+	case "08", "53":
+		return []errorutil.TagOption{errorutil.Status(http.StatusServiceUnavailable), errorutil.Retryable()}
+	}
+	return nil
+}
+
+// State returns the SQLSTATE code of an error or of its causes, or an empty string.
+func State(err error) string {
+	type causer interface {
+		Cause() error
+	}
+
+	for err != nil {
+		if state := sqlState(err, errorTypeOf(err)); state != "" {
+			return state
+		}
+		if cause, ok := err.(causer); ok {
+			err = cause.Cause()
+		} else {
+			err = errors.Unwrap(err)
+		}
+	}
+	return ""
+}
+
+// Number returns the MySQL error number of an error or of its causes, or 0.
+func Number(err error) int {
+	type causer interface {
+		Cause() error
+	}
+
+	for err != nil {
+		if number := mysqlNumber(err, errorTypeOf(err)); number != 0 {
+			return number
+		}
+		if cause, ok := err.(causer); ok {
+			err = cause.Cause()
+		} else {
+			err = errors.Unwrap(err)
+		}
+	}
+	return 0
+}
+
+// mysqlNumber returns the MySQL error number of an error of type t, read from a Number method or field, or 0.
+func mysqlNumber(err error, t *errorType) int {
+	if t.numberMethod < 0 && t.number == nil {
+		return 0
+	}
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return 0
+	}
+	if t.numberMethod >= 0 {
+		return integer(v.Method(t.numberMethod).Call(nil)[0])
+	}
+	v = structValue(v)
+	if !v.IsValid() {
+		return 0
+	}
+	number, ferr := v.FieldByIndexErr(t.number)
+	if ferr != nil {
+		return 0
+	}
+	return integer(number)
+}
+
+// isInteger reports whether t is an integer type.
+func isInteger(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// integer returns the value of an integer, or 0.
+func integer(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint())
+	}
+	return 0
+}
+
+// structValue dereferences v until a struct is found, or returns the zero Value.
+func structValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	return v
+}
+
+// sqlState returns the SQLSTATE code of an error of type t, or an empty string.
+func sqlState(err error, t *errorType) string {
+	if s, ok := err.(interface{ SQLState() string }); ok {
+		return validState(s.SQLState())
+	}
+	if t.code == nil {
+		return ""
+	}
+	v := structValue(reflect.ValueOf(err))
+	if !v.IsValid() {
+		return ""
+	}
+	code, ferr := v.FieldByIndexErr(t.code)
+	if ferr != nil {
+		return ""
+	}
+	return validState(code.String())
+}
+
+// errorType tells where the SQLSTATE code and MySQL error number of an error type are stored.
+type errorType struct {
+	// code is the index of a string field named Code, or nil
+	code []int
+	// numberMethod is the index of a Number method returning an integer, or -1
+	numberMethod int
+	// number is the index of an integer field named Number, or nil
+	number []int
+}
+
+// errorTypes caches the errorType of each error type, as Classify runs on every error inspected by errorutil.
+var errorTypes sync.Map // reflect.Type -> *errorType
+
+func errorTypeOf(err error) *errorType {
+	rt := reflect.TypeOf(err)
+	if t, ok := errorTypes.Load(rt); ok {
+		return t.(*errorType)
+	}
+	t := newErrorType(rt)
+	errorTypes.Store(rt, t)
+	return t
+}
+
+func newErrorType(rt reflect.Type) *errorType {
+	t := &errorType{numberMethod: -1}
+	if m, ok := rt.MethodByName("Number"); ok && m.Type.NumIn() == 1 && m.Type.NumOut() == 1 && isInteger(m.Type.Out(0)) {
+		t.numberMethod = m.Index
+	}
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return t
+	}
+	if f, ok := rt.FieldByName("Code"); ok && f.Type.Kind() == reflect.String {
+		t.code = f.Index
+	}
+	if f, ok := rt.FieldByName("Number"); ok && isInteger(f.Type) {
+		t.number = f.Index
+	}
+	return t
+}
+
+// validState returns state if it is a valid SQLSTATE code (5 digits or upper case letters), or an empty string.
+func validState(state string) string {
+	if len(state) != 5 {
+		return ""
+	}
+	if strings.IndexFunc(state, func(r rune) bool { return !('0' <= r && r <= '9' || 'A' <= r && r <= 'Z') }) >= 0 {
+		return ""
+	}
+	return state
+}
@@ -0,0 +1,122 @@
+package sqlutil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	oerrors "github.com/objenious/errors"
+	"github.com/objenious/errorutil"
+)
+
+// pgError mimics *pgconn.PgError (pgx).
+type pgError struct {
+	Code    string
+	Message string
+}
+
+func (err *pgError) Error() string    { return err.Message }
+func (err *pgError) SQLState() string { return err.Code }
+
+// pqErrorCode and pqError mimic pq.ErrorCode and *pq.Error (lib/pq).
+type pqErrorCode string
+
+type pqError struct {
+	Code    pqErrorCode
+	Message string
+}
+
+func (err *pqError) Error() string { return err.Message }
+
+// codeError has a Code field which is not a SQLSTATE code.
+type codeError struct {
+	Code int
+}
+
+func (err codeError) Error() string { return "foo" }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err          error
+		status       int
+		retryable    bool
+		notRetryable bool
+	}{
+		{&pgError{Code: "40001"}, http.StatusInternalServerError, true, false},
+		{&pqError{Code: "40P01"}, http.StatusInternalServerError, true, false},
+		{&pgError{Code: "23505"}, http.StatusConflict, false, true},
+		{oerrors.Wrap(&pqError{Code: "23505"}, "bar"), http.StatusConflict, false, true},
+		{fmt.Errorf("bar: %w", &pgError{Code: "23503"}), http.StatusBadRequest, false, false},
+		{&pgError{Code: "23502"}, http.StatusBadRequest, false, true},
+		{&pqError{Code: "22P02"}, http.StatusBadRequest, false, true},
+		{&pgError{Code: "57014"}, errorutil.StatusCanceled, false, false},
+		{&pgError{Code: "08006"}, http.StatusServiceUnavailable, true, false},
+		{&pqError{Code: "53300"}, http.StatusServiceUnavailable, true, false},
+		{&pgError{Code: "42P01"}, http.StatusInternalServerError, false, false},
+		{&pgError{Code: "invalid"}, http.StatusInternalServerError, false, false},
+		{codeError{Code: 23505}, http.StatusInternalServerError, false, false},
+		{errors.New("foo"), http.StatusInternalServerError, false, false},
+		{errorutil.NotFoundError(&pgError{Code: "23505"}), http.StatusNotFound, false, true},
+		{errorutil.RetryableError(&pgError{Code: "23505"}), http.StatusInternalServerError, true, false},
+	}
+	for _, tt := range tests {
+		if got := errorutil.HTTPStatusCode(tt.err); got != tt.status {
+			t.Errorf("HTTPStatusCode(%#v): got %d, want %d", tt.err, got, tt.status)
+		}
+		if got := errorutil.IsRetryable(tt.err); got != tt.retryable {
+			t.Errorf("IsRetryable(%#v): got %v, want %v", tt.err, got, tt.retryable)
+		}
+		if got := errorutil.IsNotRetryable(tt.err); got != tt.notRetryable {
+			t.Errorf("IsNotRetryable(%#v): got %v, want %v", tt.err, got, tt.notRetryable)
+		}
+		a := errorutil.Inspector{StringFallbacks: true}.Inspect(tt.err)
+		if a.Status != tt.status || a.Retryable != tt.retryable || a.NotRetryable != tt.notRetryable {
+			t.Errorf("Inspect(%#v): got %+v", tt.err, a)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{errors.New("foo"), ""},
+		{&pgError{Code: "23505"}, "23505"},
+		{oerrors.Wrap(&pqError{Code: "40P01"}, "bar"), "40P01"},
+		{fmt.Errorf("bar: %w", &pgError{Code: "57014"}), "57014"},
+		{(*pqError)(nil), ""},
+		{codeError{Code: 1}, ""},
+	}
+	for _, tt := range tests {
+		if got := State(tt.err); got != tt.want {
+			t.Errorf("State(%#v): got %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func ExampleState() {
+	err := fmt.Errorf("insert user: %w", &pgError{Code: "23505", Message: "duplicate key value violates unique constraint"})
+	fmt.Println(State(err), errorutil.HTTPStatusCode(err))
+	// Output: 23505 409
+}
+
+func BenchmarkInspect(b *testing.B) {
+	err := oerrors.Wrap(fmt.Errorf("bar: %w", errorutil.WithCode(errors.New("foo"), "foo")), "baz")
+	err = oerrors.Wrap(err, "qux")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		errorutil.Inspect(err)
+	}
+}
+
+func BenchmarkHTTPStatusCode(b *testing.B) {
+	err := oerrors.Wrap(fmt.Errorf("bar: %w", &pgError{Code: "23505"}), "baz")
+	err = oerrors.Wrap(err, "qux")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		errorutil.HTTPStatusCode(err)
+	}
+}
@@ -0,0 +1,85 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/objenious/errorutil"
+	"github.com/objenious/errorutil/backoffutil"
+)
+
+// CommitUnknownError is returned by RunInTx when a commit failed without telling whether the transaction
+// was committed (e.g. the connection was lost). It is not retryable, as running the transaction again
+// could apply it twice.
+type CommitUnknownError struct {
+	Err error
+}
+
+var _ errorutil.Retryabler = (*CommitUnknownError)(nil)
+
+func (err *CommitUnknownError) Error() string {
+	return "sqlutil: unknown commit outcome: " + err.Err.Error()
+}
+
+func (err *CommitUnknownError) Cause() error {
+	return err.Err
+}
+
+func (err *CommitUnknownError) Unwrap() error {
+	return err.Err
+}
+
+func (err *CommitUnknownError) Retryable() bool {
+	return false
+}
+
+// RunInTx runs fn in a transaction, committed if fn returns nil, rolled back otherwise.
+//
+// If the transaction fails with a retryable error (see errorutil.IsRetryable), such as a serialization failure
+// or a deadlock, it is rolled back and fn is run again in a new transaction, with exponential backoff (see backoffutil),
+// until the context is done or for at most 15 minutes. The last error is then returned.
+// fn must therefore only have side effects inside the transaction.
+//
+// A failed commit is only retried if the database reported that the transaction was rolled back
+// (serialization failure or deadlock, for Postgres and MySQL). Otherwise, a *CommitUnknownError is returned.
+func RunInTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	return backoffutil.RetryContext(ctx, func() error {
+		return runInTx(ctx, db, opts, fn)
+	})
+}
+
+func runInTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		// the commit would not be attempted
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		if rolledBack(err) {
+			return err
+		}
+		return &CommitUnknownError{Err: err}
+	}
+	return nil
+}
+
+// rolledBack reports whether a commit error guarantees that the transaction was rolled back.
+func rolledBack(err error) bool {
+	switch State(err) {
+	case "40001", "40P01":
+		return true
+	}
+	if Number(err) == 1213 {
+		// deadlock
+		return true
+	}
+	return err == sql.ErrTxDone
+}
@@ -0,0 +1,179 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/objenious/errorutil"
+)
+
+// fakeDB is a database/sql/driver.Connector whose statements and commits are scripted.
+type fakeDB struct {
+	mu                         sync.Mutex
+	begins, commits, rollbacks int
+	// exec returns the error of a statement, for the nth transaction (starting at 1)
+	exec func(n int, query string) error
+	// commit returns the error of the commit of the nth transaction
+	commit func(n int) error
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return fakeDriver{db} }
+
+type fakeDriver struct{ db *fakeDB }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{db: d.db}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.begins++
+	return &fakeTx{db: c.db, n: c.db.begins}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	n, exec := c.db.begins, c.db.exec
+	c.db.mu.Unlock()
+	if exec != nil {
+		if err := exec(n, query); err != nil {
+			return nil, err
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+	n  int
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	if tx.db.commit != nil {
+		if err := tx.db.commit(tx.n); err != nil {
+			return err
+		}
+	}
+	tx.db.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.rollbacks++
+	return nil
+}
+
+func update(tx *sql.Tx) error {
+	_, err := tx.Exec("UPDATE accounts SET balance = balance - 1")
+	return err
+}
+
+func TestRunInTx(t *testing.T) {
+	serializationFailure := &pgError{Code: "40001", Message: "could not serialize access"}
+	tests := []struct {
+		name                       string
+		exec                       func(n int, query string) error
+		commit                     func(n int) error
+		err                        func(err error) bool
+		begins, commits, rollbacks int
+	}{
+		{
+			name:   "success",
+			err:    func(err error) bool { return err == nil },
+			begins: 1, commits: 1,
+		},
+		{
+			name: "serialization failure",
+			exec: func(n int, query string) error {
+				if n == 1 {
+					return serializationFailure
+				}
+				return nil
+			},
+			err:    func(err error) bool { return err == nil },
+			begins: 2, commits: 1, rollbacks: 1,
+		},
+		{
+			name: "deadlock on commit",
+			commit: func(n int) error {
+				if n == 1 {
+					return &pqError{Code: "40P01", Message: "deadlock detected"}
+				}
+				return nil
+			},
+			err:    func(err error) bool { return err == nil },
+			begins: 2, commits: 1,
+		},
+		{
+			name:   "not retryable",
+			exec:   func(n int, query string) error { return &pgError{Code: "23505", Message: "duplicate key"} },
+			err:    func(err error) bool { return errorutil.HTTPStatusCode(err) == 409 },
+			begins: 1, rollbacks: 1,
+		},
+		{
+			name:   "unknown commit outcome",
+			commit: func(n int) error { return &pgError{Code: "08006", Message: "connection failure"} },
+			err: func(err error) bool {
+				var cerr *CommitUnknownError
+				return errors.As(err, &cerr) && errorutil.IsNotRetryable(err) && State(err) == "08006"
+			},
+			begins: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDB{exec: tt.exec, commit: tt.commit}
+			db := sql.OpenDB(fake)
+			defer db.Close()
+			err := RunInTx(context.Background(), db, nil, update)
+			if !tt.err(err) {
+				t.Errorf("RunInTx: unexpected error %v", err)
+			}
+			if fake.begins != tt.begins || fake.commits != tt.commits || fake.rollbacks != tt.rollbacks {
+				t.Errorf("RunInTx: got %d begins, %d commits, %d rollbacks, want %d, %d, %d",
+					fake.begins, fake.commits, fake.rollbacks, tt.begins, tt.commits, tt.rollbacks)
+			}
+		})
+	}
+}
+
+func TestRunInTxContext(t *testing.T) {
+	fake := &fakeDB{exec: func(n int, query string) error { return &pgError{Code: "40001", Message: "could not serialize access"} }}
+	db := sql.OpenDB(fake)
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := RunInTx(ctx, db, nil, update)
+	if State(err) != "40001" {
+		t.Errorf("RunInTx: got %v, want the last serialization failure", err)
+	}
+	if fake.commits != 0 {
+		t.Errorf("RunInTx: got %d commits, want 0", fake.commits)
+	}
+}
+
+func ExampleRunInTx() {
+	var db *sql.DB
+	err := RunInTx(context.Background(), db, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE accounts SET balance = balance - 1 WHERE id = 1")
+		return err
+	})
+	var cerr *CommitUnknownError
+	if errors.As(err, &cerr) {
+		// check whether the update was applied before doing anything else
+	}
+}
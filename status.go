@@ -0,0 +1,208 @@
+package errorutil
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// StatusCanceled is the status code of canceled operations, as used by nginx for requests closed by the client.
+const StatusCanceled = 499
+
+// WithHTTPStatus sets the HTTP status code of an error. The retryability of the error is left untouched.
+// It returns nil if the error is nil.
+func WithHTTPStatus(err error, code int) error {
+	if err == nil {
+		return nil
+	}
+	t := tag(err)
+	t.mask |= hasStatus
+	t.status = code
+	return t
+}
+
+// withStatus sets the HTTP status code of an error, and its retryability.
+func withStatus(err error, code int, retryable bool) *taggedError {
+	t := tag(err)
+	t.mask |= hasStatus | hasRetryable
+	t.status = code
+	t.retryable = retryable
+	return t
+}
+
+// Challenger defines errors carrying the authentication challenges of a StatusUnauthorized response.
+type Challenger interface {
+	Challenges() []string
+}
+
+// Challenges returns the authentication challenges of an error (i.e. implements Challenger),
+// to be sent in WWW-Authenticate headers.
+//
+// If the error is nil or has no challenges, nil is returned.
+func Challenges(err error) []string {
+	type causer interface {
+		Cause() error
+	}
+
+	for err != nil {
+		if t, ok := err.(*taggedError); ok {
+			if len(t.challenges) > 0 {
+				return t.challenges
+			}
+			err = t.err
+			continue
+		}
+		if c, ok := err.(Challenger); ok {
+			if challenges := c.Challenges(); len(challenges) > 0 {
+				return challenges
+			}
+		}
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return nil
+}
+
+// UnauthorizedError marks an error as "unauthorized", with authentication challenges such as `Bearer realm="api"`.
+// The calling http handler should return a StatusUnauthorized status code, and WriteError sets the challenges
+// in WWW-Authenticate headers. The error is not retryable. It returns nil if the error is nil.
+func UnauthorizedError(err error, challenges ...string) error {
+	if err == nil {
+		return nil
+	}
+	t := withStatus(err, http.StatusUnauthorized, false)
+	if len(challenges) > 0 {
+		t.challenges = challenges
+	}
+	return t
+}
+
+// NewUnauthorizedErrorf formats according to a format specifier and returns the string as an "unauthorized" error,
+// with an authentication challenge (ignored if empty).
+func NewUnauthorizedErrorf(challenge string, format string, args ...interface{}) error {
+	if challenge == "" {
+		return UnauthorizedError(fmt.Errorf(format, args...))
+	}
+	return UnauthorizedError(fmt.Errorf(format, args...), challenge)
+}
+
+// GoneError marks an error as "gone". The calling http handler
+// should return a StatusGone status code. The error is not retryable. It returns nil if the error is nil.
+func GoneError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return withStatus(err, http.StatusGone, false)
+}
+
+// NewGoneErrorf formats according to a format specifier and returns the string as a "gone" error.
+func NewGoneErrorf(format string, args ...interface{}) error {
+	return GoneError(fmt.Errorf(format, args...))
+}
+
+// PreconditionFailedError marks an error as "precondition failed". The calling http handler
+// should return a StatusPreconditionFailed status code. The error is not retryable. It returns nil if the error is nil.
+func PreconditionFailedError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return withStatus(err, http.StatusPreconditionFailed, false)
+}
+
+// NewPreconditionFailedErrorf formats according to a format specifier and returns the string as a "precondition failed" error.
+func NewPreconditionFailedErrorf(format string, args ...interface{}) error {
+	return PreconditionFailedError(fmt.Errorf(format, args...))
+}
+
+// PayloadTooLargeError marks an error as "payload too large". The calling http handler
+// should return a StatusRequestEntityTooLarge status code. The error is not retryable. It returns nil if the error is nil.
+func PayloadTooLargeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return withStatus(err, http.StatusRequestEntityTooLarge, false)
+}
+
+// NewPayloadTooLargeErrorf formats according to a format specifier and returns the string as a "payload too large" error.
+func NewPayloadTooLargeErrorf(format string, args ...interface{}) error {
+	return PayloadTooLargeError(fmt.Errorf(format, args...))
+}
+
+// UnprocessableEntityError marks an error as "unprocessable entity". The calling http handler
+// should return a StatusUnprocessableEntity status code. The error is not retryable. It returns nil if the error is nil.
+func UnprocessableEntityError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return withStatus(err, http.StatusUnprocessableEntity, false)
+}
+
+// NewUnprocessableEntityErrorf formats according to a format specifier and returns the string as an "unprocessable entity" error.
+func NewUnprocessableEntityErrorf(format string, args ...interface{}) error {
+	return UnprocessableEntityError(fmt.Errorf(format, args...))
+}
+
+// TooManyRequestsError marks an error as "too many requests". The calling http handler
+// should return a StatusTooManyRequests status code. The error is retryable, after retryAfter
+// (see Delay) if it is positive. It returns nil if the error is nil.
+func TooManyRequestsError(err error, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	t := withStatus(err, http.StatusTooManyRequests, true)
+	if retryAfter > 0 {
+		t.mask |= hasDelay
+		t.delay = retryAfter
+	}
+	return t
+}
+
+// NewTooManyRequestsErrorf formats according to a format specifier and returns the string as a "too many requests" error.
+func NewTooManyRequestsErrorf(retryAfter time.Duration, format string, args ...interface{}) error {
+	return TooManyRequestsError(fmt.Errorf(format, args...), retryAfter)
+}
+
+// NotImplementedError marks an error as "not implemented". The calling http handler
+// should return a StatusNotImplemented status code. The error is not retryable. It returns nil if the error is nil.
+func NotImplementedError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return withStatus(err, http.StatusNotImplemented, false)
+}
+
+// NewNotImplementedErrorf formats according to a format specifier and returns the string as a "not implemented" error.
+func NewNotImplementedErrorf(format string, args ...interface{}) error {
+	return NotImplementedError(fmt.Errorf(format, args...))
+}
+
+// ServiceUnavailableError marks an error as "service unavailable". The calling http handler
+// should return a StatusServiceUnavailable status code. The error is retryable. It returns nil if the error is nil.
+func ServiceUnavailableError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return withStatus(err, http.StatusServiceUnavailable, true)
+}
+
+// NewServiceUnavailableErrorf formats according to a format specifier and returns the string as a "service unavailable" error.
+func NewServiceUnavailableErrorf(format string, args ...interface{}) error {
+	return ServiceUnavailableError(fmt.Errorf(format, args...))
+}
+
+// GatewayTimeoutError marks an error as "gateway timeout". The calling http handler
+// should return a StatusGatewayTimeout status code. The error is retryable. It returns nil if the error is nil.
+func GatewayTimeoutError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return withStatus(err, http.StatusGatewayTimeout, true)
+}
+
+// NewGatewayTimeoutErrorf formats according to a format specifier and returns the string as a "gateway timeout" error.
+func NewGatewayTimeoutErrorf(format string, args ...interface{}) error {
+	return GatewayTimeoutError(fmt.Errorf(format, args...))
+}
@@ -0,0 +1,106 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestStatusConstructors(t *testing.T) {
+	foo := errors.New("foo")
+	tests := []struct {
+		err       error
+		status    int
+		retryable bool
+		delay     time.Duration
+	}{
+		{UnauthorizedError(foo), http.StatusUnauthorized, false, 0},
+		{NewUnauthorizedErrorf(`Bearer realm="api"`, "invalid token %s", "abc"), http.StatusUnauthorized, false, 0},
+		{GoneError(foo), http.StatusGone, false, 0},
+		{NewGoneErrorf("user %d deleted", 42), http.StatusGone, false, 0},
+		{PreconditionFailedError(foo), http.StatusPreconditionFailed, false, 0},
+		{NewPreconditionFailedErrorf("foo"), http.StatusPreconditionFailed, false, 0},
+		{PayloadTooLargeError(foo), http.StatusRequestEntityTooLarge, false, 0},
+		{NewPayloadTooLargeErrorf("foo"), http.StatusRequestEntityTooLarge, false, 0},
+		{UnprocessableEntityError(foo), http.StatusUnprocessableEntity, false, 0},
+		{NewUnprocessableEntityErrorf("foo"), http.StatusUnprocessableEntity, false, 0},
+		{TooManyRequestsError(foo, time.Minute), http.StatusTooManyRequests, true, time.Minute},
+		{TooManyRequestsError(foo, 0), http.StatusTooManyRequests, true, 0},
+		{NewTooManyRequestsErrorf(time.Second, "foo"), http.StatusTooManyRequests, true, time.Second},
+		{NotImplementedError(foo), http.StatusNotImplemented, false, 0},
+		{NewNotImplementedErrorf("foo"), http.StatusNotImplemented, false, 0},
+		{ServiceUnavailableError(foo), http.StatusServiceUnavailable, true, 0},
+		{NewServiceUnavailableErrorf("foo"), http.StatusServiceUnavailable, true, 0},
+		{GatewayTimeoutError(foo), http.StatusGatewayTimeout, true, 0},
+		{oerrors.Wrap(NewGatewayTimeoutErrorf("foo"), "bar"), http.StatusGatewayTimeout, true, 0},
+		{WithHTTPStatus(foo, http.StatusTeapot), http.StatusTeapot, false, 0},
+	}
+	for _, tt := range tests {
+		if got := HTTPStatusCode(tt.err); got != tt.status {
+			t.Errorf("HTTPStatusCode(%q): got %d, want %d", tt.err, got, tt.status)
+		}
+		if got := IsRetryable(tt.err); got != tt.retryable {
+			t.Errorf("IsRetryable(%q): got %v, want %v", tt.err, got, tt.retryable)
+		}
+		if got := Delay(tt.err); got != tt.delay {
+			t.Errorf("Delay(%q): got %v, want %v", tt.err, got, tt.delay)
+		}
+	}
+}
+
+func TestWithHTTPStatus(t *testing.T) {
+	if WithHTTPStatus(nil, http.StatusTeapot) != nil {
+		t.Errorf("WithHTTPStatus(nil): expected nil")
+	}
+	err := WithHTTPStatus(RetryableError(errors.New("foo")), http.StatusBadGateway)
+	if HTTPStatusCode(err) != http.StatusBadGateway || !IsRetryable(err) {
+		t.Errorf("WithHTTPStatus must keep the retryability, got status %d, retryable %v", HTTPStatusCode(err), IsRetryable(err))
+	}
+	if IsNotRetryable(WithHTTPStatus(errors.New("foo"), http.StatusGone)) {
+		t.Errorf("WithHTTPStatus must not set the retryability")
+	}
+}
+
+func TestStatusConstructorsNil(t *testing.T) {
+	for _, err := range []error{
+		UnauthorizedError(nil), GoneError(nil), PreconditionFailedError(nil), PayloadTooLargeError(nil), UnprocessableEntityError(nil),
+		TooManyRequestsError(nil, time.Second), NotImplementedError(nil), ServiceUnavailableError(nil), GatewayTimeoutError(nil),
+	} {
+		if err != nil {
+			t.Errorf("constructors must return nil for a nil error, got %v", err)
+		}
+	}
+}
+
+func TestChallenges(t *testing.T) {
+	challenges := []string{`Bearer realm="api"`, `Basic realm="api"`}
+	err := oerrors.Wrap(UnauthorizedError(errors.New("foo"), challenges...), "bar")
+	if got := Challenges(err); !reflect.DeepEqual(got, challenges) {
+		t.Errorf("Challenges: got %q, want %q", got, challenges)
+	}
+	if got := Challenges(UnauthorizedError(errors.New("foo"))); got != nil {
+		t.Errorf("Challenges: got %q, want nil", got)
+	}
+
+	w := httptest.NewRecorder()
+	WriteError(w, err)
+	resp := w.Result()
+	if got := resp.Header.Values("WWW-Authenticate"); !reflect.DeepEqual(got, challenges) {
+		t.Errorf("WriteError: got WWW-Authenticate %q, want %q", got, challenges)
+	}
+	got := HTTPError(resp)
+	if HTTPStatusCode(got) != http.StatusUnauthorized || !reflect.DeepEqual(Challenges(got), challenges) {
+		t.Errorf("HTTPError: got status %d, challenges %q", HTTPStatusCode(got), Challenges(got))
+	}
+}
+
+func ExampleTooManyRequestsError() {
+	var w http.ResponseWriter
+	err := TooManyRequestsError(errors.New("quota exceeded"), time.Minute)
+	WriteError(w, err) // writes a StatusTooManyRequests response, with a "Retry-After: 60" header
+}
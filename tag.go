@@ -0,0 +1,114 @@
+package errorutil
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TagOption sets an attribute of an error tagged by Tag.
+type TagOption func(t *taggedError)
+
+// Retryable marks the error as retryable.
+//
+// Unlike RetryableError, the HTTP status code is left untouched.
+func Retryable() TagOption {
+	return func(t *taggedError) {
+		t.mask |= hasRetryable
+		t.retryable = true
+	}
+}
+
+// NotRetryable marks the error as NOT retryable.
+//
+// Unlike NotRetryableError, the HTTP status code is left untouched.
+func NotRetryable() TagOption {
+	return func(t *taggedError) {
+		t.mask |= hasRetryable
+		t.retryable = false
+	}
+}
+
+// After sets the delay of the error (see Delay).
+func After(d time.Duration) TagOption {
+	return func(t *taggedError) {
+		t.mask |= hasDelay
+		t.delay = d
+	}
+}
+
+// Status sets the HTTP status code of the error (see HTTPStatusCode).
+func Status(code int) TagOption {
+	return func(t *taggedError) {
+		t.mask |= hasStatus
+		t.status = code
+	}
+}
+
+// Coded sets the machine-readable code of the error (see Code).
+func Coded(code string) TagOption {
+	return func(t *taggedError) {
+		if code != "" {
+			t.code = code
+		}
+	}
+}
+
+// Public sets the public message of the error (see PublicMessage).
+func Public(msg string) TagOption {
+	return func(t *taggedError) {
+		t.mask |= hasPublicMessage
+		t.publicMessage = msg
+	}
+}
+
+// Field sets a named value of the error (see Fields).
+func Field(key, value string) TagOption {
+	return func(t *taggedError) {
+		fields := make(map[string]string, len(t.fields)+1)
+		for k, v := range t.fields {
+			fields[k] = v
+		}
+		fields[key] = value
+		t.fields = fields
+	}
+}
+
+// Tag sets several attributes of an error at once, in a single wrapper understood by all inspection functions :
+//
+//	err = errorutil.Tag(err, errorutil.Retryable(), errorutil.After(30*time.Second), errorutil.Status(503), errorutil.Coded("db.unavailable"))
+//
+// Options are applied in order, so the last one wins when several set the same attribute.
+// It returns nil if the error is nil.
+func Tag(err error, opts ...TagOption) error {
+	if err == nil {
+		return nil
+	}
+	t := tag(err)
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// NewTagged returns an error that formats as the given text, with the attributes set by opts.
+func NewTagged(text string, opts ...TagOption) error {
+	return Tag(errors.New(text), opts...)
+}
+
+// Tagf formats according to a format specifier and returns the string as a tagged error.
+// The TagOption values found in args are applied to the error, and are not used for formatting :
+//
+//	err = errorutil.Tagf("user %s not found", id, errorutil.Status(http.StatusNotFound))
+func Tagf(format string, args ...interface{}) error {
+	var opts []TagOption
+	fargs := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if opt, ok := arg.(TagOption); ok {
+			opts = append(opts, opt)
+			continue
+		}
+		fargs = append(fargs, arg)
+	}
+	return Tag(fmt.Errorf(format, fargs...), opts...)
+}
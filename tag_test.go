@@ -0,0 +1,94 @@
+package errorutil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestTag(t *testing.T) {
+	tests := []struct {
+		err  error
+		msg  string
+		want Attributes
+	}{
+		{
+			err:  Tag(errors.New("foo"), Retryable(), After(30*time.Second), Status(503), Coded("db.unavailable")),
+			msg:  "foo",
+			want: Attributes{Status: 503, Retryable: true, Delay: 30 * time.Second, Code: "db.unavailable", PublicMessage: "Service Unavailable"},
+		},
+		{
+			err:  Tag(NotFoundError(errors.New("foo")), Retryable()),
+			msg:  "foo",
+			want: Attributes{Status: 404, Retryable: true, PublicMessage: "Not Found"},
+		},
+		{
+			err:  oerrors.Wrap(Tag(errors.New("foo"), NotRetryable(), Public("oops"), Field("id", "42")), "bar"),
+			msg:  "bar: foo",
+			want: Attributes{Status: 500, NotRetryable: true, PublicMessage: "oops", HasPublicMessage: true, Fields: map[string]string{"id": "42"}},
+		},
+		{
+			err:  Tag(RetryableError(errors.New("foo")), NotRetryable(), Status(400), Coded("")),
+			msg:  "foo",
+			want: Attributes{Status: 400, NotRetryable: true, PublicMessage: "Bad Request"},
+		},
+		{
+			err:  NewTagged("foo", Status(http.StatusTooManyRequests), After(time.Minute)),
+			msg:  "foo",
+			want: Attributes{Status: 429, Delay: time.Minute, PublicMessage: "Too Many Requests"},
+		},
+		{
+			err:  Tagf("user %s not found", "42", Status(http.StatusNotFound), Coded("user.not_found")),
+			msg:  "user 42 not found",
+			want: Attributes{Status: 404, Code: "user.not_found", PublicMessage: "Not Found"},
+		},
+	}
+	for _, tt := range tests {
+		if tt.err.Error() != tt.msg {
+			t.Errorf("Tag: got message %q, want %q", tt.err.Error(), tt.msg)
+		}
+		if got := Inspect(tt.err); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Inspect(%q): got %+v, want %+v", tt.err, got, tt.want)
+		}
+		if IsRetryable(tt.err) != tt.want.Retryable || IsNotRetryable(tt.err) != tt.want.NotRetryable || Delay(tt.err) != tt.want.Delay ||
+			HTTPStatusCode(tt.err) != tt.want.Status || Code(tt.err) != tt.want.Code {
+			t.Errorf("%q: inspection functions do not match %+v", tt.err, tt.want)
+		}
+	}
+}
+
+func TestTagNil(t *testing.T) {
+	if err := Tag(nil, Retryable()); err != nil {
+		t.Errorf("Tag(nil): got %v, want nil", err)
+	}
+}
+
+func TestTagSingleWrapper(t *testing.T) {
+	base := errors.New("foo")
+	err := Tag(Tag(base, Retryable(), Field("a", "1")), After(time.Second), Field("b", "2"))
+	if cause := err.(interface{ Cause() error }).Cause(); cause != base {
+		t.Errorf("Tag must use a single wrapper, got cause %#v", cause)
+	}
+	if want := map[string]string{"a": "1", "b": "2"}; !reflect.DeepEqual(Fields(err), want) {
+		t.Errorf("Fields: got %v, want %v", Fields(err), want)
+	}
+}
+
+func TestTagfWrap(t *testing.T) {
+	base := NotFoundError(errors.New("foo"))
+	err := Tagf("bar: %w", base, Retryable())
+	if !errors.Is(err, base) || HTTPStatusCode(err) != http.StatusNotFound || !IsRetryable(err) {
+		t.Errorf("Tagf: got %v, status %d, retryable %v", err, HTTPStatusCode(err), IsRetryable(err))
+	}
+}
+
+func ExampleTag() {
+	err := Tag(errors.New("connection refused"), Retryable(), After(30*time.Second), Status(http.StatusServiceUnavailable), Coded("db.unavailable"))
+	fmt.Println(IsRetryable(err), Delay(err), HTTPStatusCode(err), Code(err))
+	// Output: true 30s 503 db.unavailable
+}
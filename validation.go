@@ -0,0 +1,156 @@
+package errorutil
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RedactedValue replaces the rejected value of violations added with AddRedacted.
+const RedactedValue = "[redacted]"
+
+// Violation is a constraint violated by a field of a request.
+type Violation struct {
+	// Field is the path of the field, as a JSON pointer ("/user/email") or a dotted path ("user.email").
+	Field string `json:"field"`
+	// Rule is the name of the violated rule, e.g. "required" or "max_length".
+	Rule string `json:"rule"`
+	// Message describes the violation, and is safe to return to API clients.
+	Message string `json:"message"`
+	// Value is the rejected value, or RedactedValue.
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ValidationError is an error made of field violations. It is not retryable, and its status code
+// is StatusBadRequest or StatusUnprocessableEntity. WriteError renders its violations in the "errors"
+// member of the problem.
+//
+// Use NewValidation to build it. A ValidationError built as a literal has the StatusBadRequest status code.
+type ValidationError struct {
+	Violations []Violation
+	status     int
+}
+
+func (err *ValidationError) Error() string {
+	msgs := make([]string, len(err.Violations))
+	for i, v := range err.Violations {
+		msgs[i] = v.Field + ": " + v.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (err *ValidationError) HTTPStatusCode() int {
+	if err.status == 0 {
+		return http.StatusBadRequest
+	}
+	return err.status
+}
+
+func (err *ValidationError) Retryable() bool {
+	return false
+}
+
+// Violations returns the violations of a ValidationError found in the chain of an error.
+//
+// If the error is nil or has no violations, nil is returned.
+func Violations(err error) []Violation {
+	type causer interface {
+		Cause() error
+	}
+
+	for err != nil {
+		if v, ok := err.(*ValidationError); ok {
+			return v.Violations
+		}
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return nil
+}
+
+// ValidationBuilder accumulates the violations of a request.
+type ValidationBuilder struct {
+	prefix     string
+	violations *[]Violation
+	status     *int
+}
+
+// NewValidation returns a ValidationBuilder :
+//
+//	v := errorutil.NewValidation()
+//	v.Check(req.Email != "", "email", "required", "email is required", req.Email)
+//	v.Check(len(req.Name) <= 100, "name", "max_length", "name is too long", req.Name)
+//	if err := v.Err(); err != nil {
+//		errorutil.WriteError(w, err)
+//		return
+//	}
+func NewValidation() *ValidationBuilder {
+	return &ValidationBuilder{
+		violations: new([]Violation),
+		status:     new(int),
+	}
+}
+
+// Add adds a violation.
+func (b *ValidationBuilder) Add(field, rule, message string, value interface{}) *ValidationBuilder {
+	*b.violations = append(*b.violations, Violation{Field: b.path(field), Rule: rule, Message: message, Value: value})
+	return b
+}
+
+// AddRedacted adds a violation whose rejected value must not be disclosed (passwords, tokens...).
+func (b *ValidationBuilder) AddRedacted(field, rule, message string) *ValidationBuilder {
+	return b.Add(field, rule, message, RedactedValue)
+}
+
+// Check adds a violation if ok is false.
+func (b *ValidationBuilder) Check(ok bool, field, rule, message string, value interface{}) *ValidationBuilder {
+	if !ok {
+		b.Add(field, rule, message, value)
+	}
+	return b
+}
+
+// Nested returns a builder adding violations to b, for the fields of a nested struct.
+// Paths are joined with "/" if prefix is a JSON pointer, with "." otherwise.
+func (b *ValidationBuilder) Nested(prefix string) *ValidationBuilder {
+	return &ValidationBuilder{
+		prefix:     b.path(prefix),
+		violations: b.violations,
+		status:     b.status,
+	}
+}
+
+// Unprocessable sets the status code of the error to StatusUnprocessableEntity instead of StatusBadRequest.
+func (b *ValidationBuilder) Unprocessable() *ValidationBuilder {
+	*b.status = http.StatusUnprocessableEntity
+	return b
+}
+
+func (b *ValidationBuilder) path(field string) string {
+	switch {
+	case b.prefix == "":
+		return field
+	case field == "":
+		return b.prefix
+	case strings.HasPrefix(b.prefix, "/"):
+		return b.prefix + "/" + strings.TrimPrefix(field, "/")
+	default:
+		return b.prefix + "." + field
+	}
+}
+
+// Err returns a *ValidationError with the violations added so far, or nil if there are none.
+func (b *ValidationBuilder) Err() error {
+	if len(*b.violations) == 0 {
+		return nil
+	}
+	status := *b.status
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+	violations := make([]Violation, len(*b.violations))
+	copy(violations, *b.violations)
+	return &ValidationError{Violations: violations, status: status}
+}
@@ -0,0 +1,102 @@
+package errorutil
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestValidation(t *testing.T) {
+	v := NewValidation()
+	if v.Err() != nil {
+		t.Fatalf("Err: expected nil without violations")
+	}
+	v.Check(false, "email", "required", "email is required", "")
+	v.Check(true, "name", "required", "name is required", "John")
+	v.AddRedacted("password", "min_length", "password is too short")
+	v.Nested("address").Add("zip", "pattern", "invalid zip code", "ABC")
+	v.Nested("/items/0").Nested("qty").Add("", "min", "quantity must be positive", -1)
+	err := v.Err()
+
+	want := []Violation{
+		{Field: "email", Rule: "required", Message: "email is required", Value: ""},
+		{Field: "password", Rule: "min_length", Message: "password is too short", Value: RedactedValue},
+		{Field: "address.zip", Rule: "pattern", Message: "invalid zip code", Value: "ABC"},
+		{Field: "/items/0/qty", Rule: "min", Message: "quantity must be positive", Value: -1},
+	}
+	if got := Violations(oerrors.Wrap(err, "bar")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Violations: got %+v, want %+v", got, want)
+	}
+	if HTTPStatusCode(err) != http.StatusBadRequest || !IsNotRetryable(err) {
+		t.Errorf("ValidationError: got status %d, not retryable %v", HTTPStatusCode(err), IsNotRetryable(err))
+	}
+	if msg := err.Error(); msg != "validation failed: email: email is required; password: password is too short; address.zip: invalid zip code; /items/0/qty: quantity must be positive" {
+		t.Errorf("Error: got %q", msg)
+	}
+
+	v.Add("age", "min", "too young", 3)
+	if len(Violations(err)) != 4 {
+		t.Errorf("Err must return a snapshot of the violations")
+	}
+	if HTTPStatusCode(v.Unprocessable().Err()) != http.StatusUnprocessableEntity {
+		t.Errorf("Unprocessable: got status %d", HTTPStatusCode(v.Err()))
+	}
+	if Violations(errors.New("foo")) != nil {
+		t.Errorf("Violations: expected nil for errors without violations")
+	}
+}
+
+func TestValidationErrorLiteral(t *testing.T) {
+	err := &ValidationError{Violations: []Violation{{Field: "email", Rule: "required", Message: "email is required"}}}
+	if got := HTTPStatusCode(err); got != http.StatusBadRequest {
+		t.Errorf("HTTPStatusCode: got %d, want %d", got, http.StatusBadRequest)
+	}
+	w := httptest.NewRecorder()
+	WriteError(w, err)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("WriteError: got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestWriteValidationError(t *testing.T) {
+	err := NewValidation().Add("email", "format", "invalid email", "foo@").AddRedacted("token", "format", "invalid token").Err()
+	w := httptest.NewRecorder()
+	WriteError(w, err)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("WriteError: got status %d", w.Code)
+	}
+	var p struct {
+		Errors []map[string]interface{} `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("invalid body %s: %v", w.Body, err)
+	}
+	want := []map[string]interface{}{
+		{"field": "email", "rule": "format", "message": "invalid email", "value": "foo@"},
+		{"field": "token", "rule": "format", "message": "invalid token", "value": RedactedValue},
+	}
+	if !reflect.DeepEqual(p.Errors, want) {
+		t.Errorf("WriteError: got errors %v, want %v", p.Errors, want)
+	}
+}
+
+func ExampleNewValidation() {
+	var w http.ResponseWriter
+	var req struct {
+		Email    string
+		Password string
+	}
+	v := NewValidation()
+	v.Check(req.Email != "", "email", "required", "email is required", req.Email)
+	if len(req.Password) < 12 {
+		v.AddRedacted("password", "min_length", "password is too short")
+	}
+	if err := v.Err(); err != nil {
+		WriteError(w, err) // writes the violations in the "errors" member of the problem
+	}
+}
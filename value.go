@@ -0,0 +1,131 @@
+package errorutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// keyValue is a value attached to an error by WithValue.
+// Values decoded by Unmarshal have no key, only the name of their key and their JSON encoding.
+type keyValue struct {
+	key  interface{}
+	val  interface{}
+	name string
+	raw  json.RawMessage
+}
+
+// WithValue attaches a value to an error, associated with key, like context.WithValue.
+// It returns nil if the error is nil.
+//
+// The key must be comparable, and should be of an unexported type to avoid collisions between packages :
+//
+//	type tenantKey struct{}
+//
+//	err = errorutil.WithValue(err, tenantKey{}, tenantID)
+//	tenantID, ok := errorutil.Value[string](err, tenantKey{})
+//
+// Values are encoded by Marshal if their key implements fmt.Stringer, under the name returned by String
+// (which should be qualified by the package, e.g. "billing.tenant"), and if they can be encoded to JSON.
+// After Unmarshal, Value decodes them from JSON.
+func WithValue(err error, key, val interface{}) error {
+	if err == nil {
+		return nil
+	}
+	if key == nil {
+		panic("errorutil: nil key")
+	}
+	if !reflect.TypeOf(key).Comparable() {
+		panic("errorutil: key is not comparable")
+	}
+	t := tag(err)
+	values := make([]keyValue, len(t.values), len(t.values)+1)
+	copy(values, t.values)
+	t.values = append(values, keyValue{key: key, val: val})
+	return t
+}
+
+// Value returns the value associated with key by WithValue, searching the error and its causes.
+// When several errors of the chain define the same key, the outermost value is returned.
+//
+// If the error is nil, no value is associated with key or the value is not a T, ok is false.
+func Value[T any](err error, key interface{}) (v T, ok bool) {
+	type causer interface {
+		Cause() error
+	}
+
+	name := keyName(key)
+	for err != nil {
+		if t, ok := err.(*taggedError); ok {
+			for i := len(t.values) - 1; i >= 0; i-- {
+				kv := t.values[i]
+				if kv.key != nil && kv.key == key {
+					v, ok := kv.val.(T)
+					return v, ok
+				}
+				if kv.key == nil && name != "" && kv.name == name {
+					if err := json.Unmarshal(kv.raw, &v); err != nil {
+						var zero T
+						return zero, false
+					}
+					return v, true
+				}
+			}
+		}
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return v, false
+}
+
+// keyName returns the name under which the values of a key are marshaled, or an empty string.
+func keyName(key interface{}) string {
+	if s, ok := key.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return ""
+}
+
+// marshalValues returns the JSON encoding of the values of an error and its causes, by key name.
+// Values whose key has no name or that cannot be encoded are skipped.
+func marshalValues(err error) map[string]json.RawMessage {
+	type causer interface {
+		Cause() error
+	}
+
+	var values map[string]json.RawMessage
+	for err != nil {
+		if t, ok := err.(*taggedError); ok {
+			for i := len(t.values) - 1; i >= 0; i-- {
+				kv := t.values[i]
+				name, raw := kv.name, kv.raw
+				if kv.key != nil {
+					name = keyName(kv.key)
+					if name == "" {
+						continue
+					}
+					var err error
+					if raw, err = json.Marshal(kv.val); err != nil {
+						continue
+					}
+				}
+				if _, ok := values[name]; ok {
+					continue
+				}
+				if values == nil {
+					values = map[string]json.RawMessage{}
+				}
+				values[name] = raw
+			}
+		}
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return values
+}
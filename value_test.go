@@ -0,0 +1,98 @@
+package errorutil
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	oerrors "github.com/objenious/errors"
+)
+
+type tenantKey struct{}
+
+func (tenantKey) String() string { return "errorutil.tenant" }
+
+type slaKey struct{}
+
+func (slaKey) String() string { return "errorutil.sla" }
+
+type localKey struct{}
+
+type sla struct {
+	Class    string        `json:"class"`
+	Deadline time.Duration `json:"deadline"`
+}
+
+func TestValue(t *testing.T) {
+	base := errors.New("foo")
+	err := oerrors.Wrap(WithValue(RetryableError(WithValue(base, tenantKey{}, "inner")), tenantKey{}, "acme"), "bar")
+	err = WithValue(err, localKey{}, 42)
+
+	if v, ok := Value[string](err, tenantKey{}); !ok || v != "acme" {
+		t.Errorf("Value(tenantKey): got %q, %v, want %q, true", v, ok, "acme")
+	}
+	if v, ok := Value[int](err, localKey{}); !ok || v != 42 {
+		t.Errorf("Value(localKey): got %d, %v, want 42, true", v, ok)
+	}
+	if v, ok := Value[string](err, localKey{}); ok {
+		t.Errorf("Value[string](localKey): got %q, want no value of this type", v)
+	}
+	if _, ok := Value[string](err, slaKey{}); ok {
+		t.Errorf("Value(slaKey): unexpected value")
+	}
+	if _, ok := Value[string](nil, tenantKey{}); ok {
+		t.Errorf("Value(nil): unexpected value")
+	}
+	if WithValue(nil, tenantKey{}, "acme") != nil {
+		t.Errorf("WithValue(nil): expected nil")
+	}
+	if !IsRetryable(err) {
+		t.Errorf("WithValue must keep the other tags")
+	}
+}
+
+func TestValueMarshal(t *testing.T) {
+	err := WithValue(WithValue(NotFoundError(errors.New("foo")), tenantKey{}, "acme"), slaKey{}, sla{Class: "gold", Deadline: time.Second})
+	err = WithValue(err, localKey{}, 42)
+	err = WithValue(err, tenantKey{}, func() {}) // cannot be encoded, the inner value is used
+	b, merr := Marshal(err)
+	if merr != nil {
+		t.Fatalf("Marshal: unexpected error %v", merr)
+	}
+	got := Unmarshal(b)
+	if v, ok := Value[string](got, tenantKey{}); !ok || v != "acme" {
+		t.Errorf("Value(tenantKey): got %q, %v in %s", v, ok, b)
+	}
+	if v, ok := Value[sla](got, slaKey{}); !ok || v != (sla{Class: "gold", Deadline: time.Second}) {
+		t.Errorf("Value(slaKey): got %+v, %v in %s", v, ok, b)
+	}
+	if v, ok := Value[int](got, slaKey{}); ok {
+		t.Errorf("Value[int](slaKey): got %d, want no value of this type", v)
+	}
+	if _, ok := Value[int](got, localKey{}); ok {
+		t.Errorf("Value(localKey): unnamed keys must not be marshaled, got %s", b)
+	}
+}
+
+func TestWithValueInvalidKey(t *testing.T) {
+	for _, key := range []interface{}{nil, []string{"a"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithValue(%v): expected a panic", key)
+				}
+			}()
+			WithValue(errors.New("foo"), key, "bar")
+		}()
+	}
+}
+
+type upstreamKey struct{}
+
+func ExampleValue() {
+	err := WithValue(errors.New("connection refused"), upstreamKey{}, "billing")
+	upstream, _ := Value[string](err, upstreamKey{})
+	fmt.Println(upstream)
+	// Output: billing
+}
@@ -0,0 +1,79 @@
+package errorutil
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Versioner defines errors carrying the current and expected versions (or ETags) of a resource,
+// returned by optimistic concurrency checks.
+type Versioner interface {
+	Versions() (current, expected string)
+}
+
+// VersionConflictError marks an error as a version conflict : the resource was modified concurrently,
+// its current version is current while the client expected expected. The calling http handler should return
+// a StatusConflict status code, and WriteError sets the ETag header to the current version.
+// The error is not retryable as is : the client has to read the resource again. It returns nil if the error is nil.
+func VersionConflictError(err error, current, expected string) error {
+	if err == nil {
+		return nil
+	}
+	return withVersions(withStatus(err, http.StatusConflict, false), current, expected)
+}
+
+// ETagMismatchError marks an error as a failed If-Match precondition : the current ETag of the resource is current,
+// while the request expected expected. The calling http handler should return a StatusPreconditionFailed status code,
+// and WriteError sets the ETag header to the current ETag. The error is not retryable. It returns nil if the error is nil.
+func ETagMismatchError(err error, current, expected string) error {
+	if err == nil {
+		return nil
+	}
+	return withVersions(withStatus(err, http.StatusPreconditionFailed, false), current, expected)
+}
+
+func withVersions(t *taggedError, current, expected string) *taggedError {
+	t.mask |= hasVersions
+	t.currentVersion = current
+	t.expectedVersion = expected
+	return t
+}
+
+// Versions returns the current and expected versions of a resource carried by an error (i.e. implements Versioner).
+// ok is false if the error is nil or has no versions.
+//
+// For errors returned by HTTPError for StatusConflict and StatusPreconditionFailed responses, current is the ETag
+// header of the response, as sent by the server (e.g. `"42"`), ready to be used in a If-Match header, and expected is empty.
+func Versions(err error) (current, expected string, ok bool) {
+	type causer interface {
+		Cause() error
+	}
+
+	for err != nil {
+		if t, ok := err.(*taggedError); ok {
+			if t.mask&hasVersions != 0 {
+				return t.currentVersion, t.expectedVersion, true
+			}
+			err = t.err
+			continue
+		}
+		if v, ok := err.(Versioner); ok {
+			current, expected := v.Versions()
+			return current, expected, true
+		}
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return "", "", false
+}
+
+// entityTag formats a version as an entity tag, quoting it unless it is already an entity tag.
+func entityTag(version string) string {
+	if strings.HasPrefix(version, `"`) || strings.HasPrefix(version, `W/"`) {
+		return version
+	}
+	return `"` + version + `"`
+}
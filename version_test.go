@@ -0,0 +1,91 @@
+package errorutil
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	oerrors "github.com/objenious/errors"
+)
+
+func TestVersions(t *testing.T) {
+	tests := []struct {
+		err               error
+		status            int
+		current, expected string
+		ok                bool
+	}{
+		{nil, http.StatusOK, "", "", false},
+		{errors.New("foo"), http.StatusInternalServerError, "", "", false},
+		{ConflictError(errors.New("foo")), http.StatusConflict, "", "", false},
+		{VersionConflictError(errors.New("foo"), "42", "41"), http.StatusConflict, "42", "41", true},
+		{oerrors.Wrap(VersionConflictError(errors.New("foo"), "42", "41"), "bar"), http.StatusConflict, "42", "41", true},
+		{ETagMismatchError(errors.New("foo"), `"abc"`, `"abd"`), http.StatusPreconditionFailed, `"abc"`, `"abd"`, true},
+	}
+	for _, tt := range tests {
+		current, expected, ok := Versions(tt.err)
+		if current != tt.current || expected != tt.expected || ok != tt.ok {
+			t.Errorf("Versions(%q): got %q, %q, %v, want %q, %q, %v", tt.err, current, expected, ok, tt.current, tt.expected, tt.ok)
+		}
+		if got := HTTPStatusCode(tt.err); got != tt.status {
+			t.Errorf("HTTPStatusCode(%q): got %d, want %d", tt.err, got, tt.status)
+		}
+		if tt.ok && !IsNotRetryable(tt.err) {
+			t.Errorf("IsNotRetryable(%q): version errors must not be retryable", tt.err)
+		}
+	}
+	if VersionConflictError(nil, "1", "2") != nil || ETagMismatchError(nil, "1", "2") != nil {
+		t.Errorf("constructors must return nil for a nil error")
+	}
+}
+
+func TestVersionsRoundTrip(t *testing.T) {
+	tests := []struct {
+		err  error
+		etag string
+	}{
+		{VersionConflictError(errors.New("foo"), "42", "41"), `"42"`},
+		{ETagMismatchError(errors.New("foo"), `W/"abc"`, `W/"abd"`), `W/"abc"`},
+		{VersionConflictError(errors.New("foo"), "", "41"), ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		WriteError(w, tt.err)
+		resp := w.Result()
+		if got := resp.Header.Get("ETag"); got != tt.etag {
+			t.Errorf("WriteError(%q): got ETag %q, want %q", tt.err, got, tt.etag)
+		}
+		err := HTTPError(resp)
+		current, expected, ok := Versions(err)
+		if current != tt.etag || expected != "" || ok != (tt.etag != "") {
+			t.Errorf("HTTPError: got versions %q, %q, %v, want %q", current, expected, ok, tt.etag)
+		}
+		if HTTPStatusCode(err) != HTTPStatusCode(tt.err) {
+			t.Errorf("HTTPError: got status %d, want %d", HTTPStatusCode(err), HTTPStatusCode(tt.err))
+		}
+	}
+
+	resp := &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{"Etag": {`"1"`}}}
+	if _, _, ok := Versions(HTTPError(resp)); ok {
+		t.Errorf("HTTPError: the ETag of responses other than 409 and 412 must be ignored")
+	}
+}
+
+func ExampleVersionConflictError() {
+	var w http.ResponseWriter
+	current, expected := "42", "41"
+	if current != expected {
+		WriteError(w, VersionConflictError(errors.New("user was modified"), current, expected)) // sets the ETag header to "42"
+	}
+}
+
+func ExampleVersions() {
+	var resp *http.Response // response to a PUT with a If-Match header
+	if err := HTTPError(resp); err != nil {
+		if etag, _, ok := Versions(err); ok {
+			// read the resource again, and retry the update with If-Match: etag
+			_ = etag
+		}
+	}
+}